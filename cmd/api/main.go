@@ -46,6 +46,7 @@ type config struct {
 	readRequestTimeout  time.Duration
 	writeRequestTimeout time.Duration
 	clientUrl           string
+	trustProxyHeaders   bool // only behind a proxy that sets X-Forwarded-For / X-Real-IP itself
 	dbConfig            dbConfig
 	mailerConfig        mailerConfig
 	redisConfig         redisConfig
@@ -92,6 +93,7 @@ func loadConfig() (*config, error) {
 		readRequestTimeout:  time.Second * 15,
 		writeRequestTimeout: time.Second * 15,
 		clientUrl:           clientUrl,
+		trustProxyHeaders:   os.Getenv("TRUST_PROXY_HEADERS") == "true",
 		dbConfig: dbConfig{
 			dbConnStr:       dbConnStr,
 			maxOpenConns:    25,
@@ -152,11 +154,19 @@ func main() {
 	r := chi.NewRouter()
 
 	r.Use(c.Handler)
+	// the client ip is taken from the proxy headers only when a trusted proxy sets them,
+	// otherwise clients could spoof the ip recorded for their sessions
+	if cfg.trustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
 	r.Use(middleware.Logger)
 	r.Route("/api", func(r chi.Router) {
 
 		r.Get("/health", handler.HealthCheckHandler)
 
+		r.With(handler.OptionalAuthMiddleware).Get("/search", handler.SearchHandler)
+		r.Get("/autocomplete", handler.AutocompleteHandler)
+
 		r.Route("/file", func(r chi.Router) {
 			r.Use(handler.AuthMiddleware)
 			r.Post("/upload", handler.UserImageFileUploadHandler)
@@ -167,9 +177,20 @@ func main() {
 			r.Post("/register", handler.RegisterUserHandler)
 			r.Put("/activate/{token}", handler.ActivateUserHandler)
 			r.Post("/login", handler.LoginUserHandler)
+			r.Post("/refresh", handler.RefreshTokenHandler)
+			r.Post("/resend-verification", handler.ResendVerificationHandler)
+			r.Post("/forgot-password", handler.ForgotPasswordHandler)
+			r.Put("/reset-password/{token}", handler.ResetPasswordHandler)
 			r.With(handler.AuthMiddleware).Get("/user", handler.GetAuthUserHandler)
 			r.With(handler.AuthMiddleware).Get("/logout", handler.LogoutHandler)
 
+			r.Route("/sessions", func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Get("/", handler.GetSessionsHandler)
+				r.Delete("/", handler.RevokeAllSessionsHandler)
+				r.Delete("/{sessionId}", handler.RevokeSessionHandler)
+			})
+
 		})
 
 		r.Route("/topics", func(r chi.Router) {
@@ -205,13 +226,71 @@ func main() {
 				r.Post("/", handler.CreateCommunityHandler)
 				r.Post("/{communityId}/join", handler.ToggleJoinCommunityHandler)
 				r.Get("/{communityId}/members", handler.GetCommunityMembersHandler)
+				r.Post("/{communityId}/transfer/accept", handler.AcceptCommunityOwnershipTransferHandler)
+				r.Post("/{communityId}/transfer/decline", handler.DeclineCommunityOwnershipTransferHandler)
+
+				r.Group(func(r chi.Router) {
+					r.Use(handler.CommunityOwnerMiddleware)
+					r.Patch("/{communityId}", handler.UpdateCommunityHandler)
+					r.Delete("/{communityId}", handler.DeleteCommunityHandler)
+					r.Post("/{communityId}/transfer", handler.TransferCommunityOwnershipHandler)
+					r.Delete("/{communityId}/transfer", handler.CancelCommunityOwnershipTransferHandler)
+				})
 			})
 
+			r.Route("/{communityId}/moderators", func(r chi.Router) {
+
+				r.Get("/", handler.GetCommunityModeratorsHandler)
+
+				r.Group(func(r chi.Router) {
+					r.Use(handler.AuthMiddleware)
+					r.Use(handler.CommunityOwnerMiddleware)
+					r.Put("/{userId}", handler.AddCommunityModeratorHandler)
+					r.Delete("/{userId}", handler.RemoveCommunityModeratorHandler)
+				})
+			})
+
+			r.Route("/{communityId}/join-requests", func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Use(handler.CommunityModeratorMiddleware)
+				r.Get("/", handler.GetCommunityJoinRequestsHandler)
+				r.Post("/{userId}/approve", handler.ApproveJoinRequestHandler)
+				r.Post("/{userId}/reject", handler.RejectJoinRequestHandler)
+			})
+
+			r.Route("/{communityId}/invites", func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Use(handler.CommunityModeratorMiddleware)
+				r.Get("/", handler.GetCommunityInvitesHandler)
+				r.Post("/", handler.CreateCommunityInviteHandler)
+				r.Delete("/{inviteId}", handler.DeleteCommunityInviteHandler)
+			})
+
+			r.Route("/{communityId}/bans", func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Use(handler.CommunityModeratorMiddleware)
+				r.Get("/", handler.GetCommunityBansHandler)
+				r.Put("/{userId}", handler.BanCommunityUserHandler)
+				r.Delete("/{userId}", handler.UnbanCommunityUserHandler)
+			})
+
+			r.Route("/{communityId}/reports", func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Use(handler.CommunityModeratorMiddleware)
+				r.Get("/", handler.GetCommunityReportsHandler)
+				r.Post("/{reportId}/resolve", handler.ResolveCommunityReportHandler)
+			})
+
+			r.With(handler.AuthMiddleware, handler.CommunityModeratorMiddleware).Get("/{communityId}/audit-logs", handler.GetCommunityAuditLogsHandler)
+
+			r.Get("/{communityId}/reactions", handler.GetCommunityReactionsHandler)
+			r.With(handler.AuthMiddleware, handler.CommunityModeratorMiddleware).Put("/{communityId}/reactions", handler.UpdateCommunityReactionsHandler)
+
 			// join community route
 
 			r.Route("/{communityId}/posts", func(r chi.Router) {
 
-				r.Get("/", handler.GetCommunityPostsHandler)
+				r.With(handler.OptionalAuthMiddleware).Get("/", handler.GetCommunityPostsHandler)
 
 				r.Group(func(r chi.Router) {
 					r.Use(handler.AuthMiddleware)
@@ -224,8 +303,8 @@ func main() {
 
 		r.Route("/posts", func(r chi.Router) {
 
-			r.With(handler.AuthMiddleware).Get("/feed", handler.GetUserPostsFeedHandler) // feed for logged in users consisting of posts of top communities they are a part of
-			r.Get("/explore", handler.GetPostsFeedHandler)                               // for all users (no personalized according to joined communities)
+			r.With(handler.AuthMiddleware).Get("/feed", handler.GetUserPostsFeedHandler)        // feed for logged in users consisting of posts of top communities they are a part of
+			r.With(handler.OptionalAuthMiddleware).Get("/explore", handler.GetPostsFeedHandler) // for all users (no personalized according to joined communities)
 
 			r.Group(func(r chi.Router) {
 
@@ -234,13 +313,21 @@ func main() {
 					r.Group(func(r chi.Router) {
 						r.Use(handler.AuthMiddleware)
 						r.Post("/like", handler.TogglePostLikeHandler)
+						r.Put("/vote", handler.VotePostHandler)
+						r.Post("/reactions", handler.TogglePostReactionHandler)
 						r.Post("/bookmark", handler.TogglePostBookmarkHandler)
+						r.Post("/report", handler.ReportPostHandler)
+						r.Patch("/", handler.UpdatePostHandler)
 					})
 
+					r.With(handler.OptionalAuthMiddleware).Get("/", handler.GetPostHandler)
+					r.With(handler.OptionalAuthMiddleware).Get("/revisions", handler.GetPostRevisionsHandler)
+
 					r.Route("/comments", func(r chi.Router) {
 
-						r.Get("/", handler.GetPostCommentsHandler)
-						r.Get("/{commentId}/replies", handler.GetCommentRepliesHandler)
+						r.With(handler.OptionalAuthMiddleware).Get("/", handler.GetPostCommentsHandler)
+						r.With(handler.OptionalAuthMiddleware).Get("/tree", handler.GetPostCommentTreeHandler)
+						r.With(handler.OptionalAuthMiddleware).Get("/{commentId}/replies", handler.GetCommentRepliesHandler)
 
 						r.Group(func(r chi.Router) {
 							r.Use(handler.AuthMiddleware)
@@ -253,16 +340,61 @@ func main() {
 			})
 		})
 
-		r.Route("/comments", func(r chi.Router) {
+		// site-wide moderation queue
+		r.Route("/reports", func(r chi.Router) {
 			r.Use(handler.AuthMiddleware)
-			r.Delete("/{commentId}", handler.DeletePostCommentHandler)
-			r.Post("/{commentId}/like", handler.ToggleCommentLikeHandler)
+			r.Use(handler.AdminMiddleware)
+			r.Get("/", handler.GetReportsHandler)
+			r.Post("/{reportId}/resolve", handler.ResolveReportHandler)
 		})
 
-		r.Route("/users", func(r chi.Router) {
-			// create a put handler to update authenticated user's username
+		r.With(handler.AuthMiddleware, handler.AdminMiddleware).Get("/audit-logs", handler.GetAuditLogsHandler)
+
+		r.Route("/invites", func(r chi.Router) {
 			r.Use(handler.AuthMiddleware)
-			r.Patch("/me/username", handler.UpdateUsernameHandler)
+			r.Post("/{inviteCode}/accept", handler.AcceptCommunityInviteHandler)
+		})
+
+		r.Route("/comments", func(r chi.Router) {
+			r.With(handler.OptionalAuthMiddleware).Get("/{commentId}/revisions", handler.GetCommentRevisionsHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Patch("/{commentId}", handler.UpdatePostCommentHandler)
+				r.Delete("/{commentId}", handler.DeletePostCommentHandler)
+				r.Post("/{commentId}/like", handler.ToggleCommentLikeHandler)
+				r.Put("/{commentId}/vote", handler.VoteCommentHandler)
+				r.Post("/{commentId}/reactions", handler.ToggleCommentReactionHandler)
+				r.Post("/{commentId}/report", handler.ReportCommentHandler)
+			})
+		})
+
+		r.Route("/users", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Patch("/me", handler.UpdateProfileHandler)
+				r.Patch("/me/username", handler.UpdateUsernameHandler)
+				r.Delete("/me", handler.DeleteAccountHandler)
+				r.Get("/me/ownership-transfers", handler.GetOwnershipTransfersHandler)
+				r.Get("/me/likes", handler.GetLikedPostsHandler)
+				r.Get("/me/bookmarks", handler.GetBookmarkedPostsHandler)
+				r.Patch("/me/bookmarks/{postId}", handler.UpdatePostBookmarkCollectionHandler) // move a bookmark into or out of a collection
+
+				r.Route("/me/bookmark-collections", func(r chi.Router) {
+					r.Get("/", handler.GetBookmarkCollectionsHandler)
+					r.Post("/", handler.CreateBookmarkCollectionHandler)
+					r.Patch("/{collectionId}", handler.UpdateBookmarkCollectionHandler)
+					r.Delete("/{collectionId}", handler.DeleteBookmarkCollectionHandler)
+				})
+			})
+
+			// public profile of a user and their activity
+			r.Route("/{username}", func(r chi.Router) {
+				r.Get("/", handler.GetUserProfileHandler)
+				r.With(handler.OptionalAuthMiddleware).Get("/posts", handler.GetUserProfilePostsHandler)
+				r.With(handler.OptionalAuthMiddleware).Get("/comments", handler.GetUserProfileCommentsHandler)
+				r.Get("/communities", handler.GetUserProfileCommunitiesHandler)
+			})
 		})
 
 	})
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+)
+
+const (
+	cleanupInterval = time.Hour
+
+	// unverified accounts are kept this long so users can still request a new verification mail
+	unverifiedUserGracePeriod = time.Hour * 24 * 7
+)
+
+// runs the periodic database cleanup, once at startup and then every cleanupInterval
+func runScheduledCleanup(storage *storage.Storage) {
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		cleanupStaleAccounts(storage)
+		<-ticker.C
+	}
+}
+
+func cleanupStaleAccounts(storage *storage.Storage) {
+
+	deletedInvitations, err := storage.Users.DeleteExpiredUserInvitations()
+	if err != nil {
+		log.Printf("Error deleting expired user invitations: %v\n", err)
+	} else if deletedInvitations > 0 {
+		log.Printf("Deleted %d expired user invitations\n", deletedInvitations)
+	}
+
+	deletedUsers, err := storage.Users.DeleteStaleUnverifiedUsers(time.Now().Add(-unverifiedUserGracePeriod))
+	if err != nil {
+		log.Printf("Error deleting unverified users: %v\n", err)
+	} else if deletedUsers > 0 {
+		log.Printf("Deleted %d unverified users\n", deletedUsers)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+)
+
+// hot feeds rank posts by their stored hot score, which decays with age so it has to be recomputed
+const hotScoreInterval = time.Minute
+
+// recomputes the hot scores of posts, once at startup and then every hotScoreInterval
+func runHotScoreUpdates(storage *storage.Storage) {
+
+	ticker := time.NewTicker(hotScoreInterval)
+	defer ticker.Stop()
+
+	for {
+		updateHotScores(storage)
+		<-ticker.C
+	}
+}
+
+func updateHotScores(storage *storage.Storage) {
+
+	if _, err := storage.Posts.UpdateHotScores(); err != nil {
+		log.Printf("Error updating hot scores: %v\n", err)
+	}
+}
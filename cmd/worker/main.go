@@ -9,9 +9,13 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/dhruv15803/go-community-platform/internal/database"
 	"github.com/dhruv15803/go-community-platform/internal/mailer"
 	"github.com/dhruv15803/go-community-platform/internal/redis"
+	"github.com/dhruv15803/go-community-platform/internal/s3"
+	"github.com/dhruv15803/go-community-platform/internal/storage"
 	"github.com/joho/godotenv"
+	goredis "github.com/redis/go-redis/v9"
 )
 
 type mailerConfig struct {
@@ -27,9 +31,19 @@ type redisConfig struct {
 	db       int
 }
 
+type dbConfig struct {
+	dbConnStr       string
+	maxOpenConns    int
+	maxIdleConns    int
+	maxConnLifetime time.Duration
+	maxConnIdleTime time.Duration
+}
+
 type config struct {
 	redisConfig  redisConfig
 	mailerConfig mailerConfig
+	dbConfig     dbConfig
+	s3Bucket     string
 }
 
 func loadConfig() (*config, error) {
@@ -42,6 +56,8 @@ func loadConfig() (*config, error) {
 	mailerPassword := os.Getenv("MAILER_PASSWORD")
 	redisAddr := os.Getenv("REDIS_ADDR")
 	redisPassword := os.Getenv("REDIS_PASSWORD")
+	s3Bucket := os.Getenv("AWS_S3_BUCKET")
+	dbConnStr := os.Getenv("POSTGRES_DB_CONN")
 
 	mailerPort, err := strconv.Atoi(mailerPortStr)
 	if err != nil {
@@ -56,6 +72,14 @@ func loadConfig() (*config, error) {
 		return nil, errors.New("$REDIS_ADDR not set")
 	}
 
+	if s3Bucket == "" {
+		return nil, errors.New("$AWS_S3_BUCKET not set")
+	}
+
+	if dbConnStr == "" {
+		return nil, errors.New("$POSTGRES_DB_CONN not set")
+	}
+
 	return &config{
 		redisConfig: redisConfig{
 			addr:     redisAddr,
@@ -68,6 +92,14 @@ func loadConfig() (*config, error) {
 			username: mailerUsername,
 			password: mailerPassword,
 		},
+		dbConfig: dbConfig{
+			dbConnStr:       dbConnStr,
+			maxOpenConns:    5,
+			maxIdleConns:    2,
+			maxConnLifetime: time.Hour,
+			maxConnIdleTime: time.Minute * 10,
+		},
+		s3Bucket: s3Bucket,
 	}, nil
 }
 
@@ -88,66 +120,107 @@ func main() {
 
 	log.Println("Connected to redis")
 
-	for {
+	s3Client, err := s3.NewS3().NewClient()
+	if err != nil {
+		log.Fatalf("Error connecting to s3: %v\n", err)
+	}
 
-		type VerificationMailJob struct {
-			FromEmail         string `json:"from_email"`
-			ToEmail           string `json:"to_email"`
-			UserId            int    `json:"user_id"`
-			Subject           string `json:"subject"`
-			EmailTemplatePath string `json:"email_template_path"`
-			Token             string `json:"token"`
-		}
+	db, err := database.NewPostgresConn(cfg.dbConfig.dbConnStr, cfg.dbConfig.maxOpenConns, cfg.dbConfig.maxIdleConns, cfg.dbConfig.maxConnLifetime, cfg.dbConfig.maxConnIdleTime).Connect()
+	if err != nil {
+		log.Fatalf("Error connecting to postgres database: %v\n", err)
+	}
+	defer db.Close()
+
+	log.Println("Connected to postgres database")
 
-		result, err := rdb.BRPop(context.Background(), 0, "queue:email").Result()
+	go runScheduledCleanup(storage.NewStorage(db))
+	go runHotScoreUpdates(storage.NewStorage(db))
+
+	for {
+
+		result, err := rdb.BRPop(context.Background(), 0, "queue:email", "queue:s3:cleanup").Result()
 		if err != nil {
 			log.Printf("Error retrieving queue element: %v\n", err)
 			continue
 		}
-		var verificationMailJob VerificationMailJob
-		verificationMailJobStr := result[1]
-		if err := json.Unmarshal([]byte(verificationMailJobStr), &verificationMailJob); err != nil {
-			log.Printf("Error unmarshalling mail job: %v\n", err)
-			//	push popped job to a dlq
-			return
-		}
 
-		maxEmailRetries := 3
-		isEmailSent := false
-		for i := 0; i < maxEmailRetries; i++ {
+		queue, job := result[0], result[1]
 
-			if err := mailer.SendVerificationMail(verificationMailJob.FromEmail, verificationMailJob.ToEmail, verificationMailJob.Subject, verificationMailJob.Token, verificationMailJob.EmailTemplatePath); err != nil {
-				log.Printf("Error sending verification mail, attempt %d : %v\n", i+1, err)
-				continue
-			}
-			isEmailSent = true
-			break
+		switch queue {
+		case "queue:email":
+			processMailJob(rdb, mailer, job)
+		case "queue:s3:cleanup":
+			processS3CleanupJob(rdb, s3Client, cfg.s3Bucket, job)
 		}
+	}
+}
 
-		if !isEmailSent {
+func processMailJob(rdb *goredis.Client, mailer *mailer.Mailer, mailJobStr string) {
 
-			log.Printf("Error sending verification mail, attempt %d : %v\n", maxEmailRetries, err)
+	var mailJob MailJob
+	if err := json.Unmarshal([]byte(mailJobStr), &mailJob); err != nil {
+		log.Printf("Error unmarshalling mail job: %v\n", err)
+		//	push popped job to a dlq
+		return
+	}
 
-			type VerificationMailJobFailureDetail struct {
-				UserId    int                 `json:"user_id"`
-				UserEmail string              `json:"user_email"`
-				TimeStamp time.Time           `json:"timestamp"`
-				Job       VerificationMailJob `json:"job"`
-			}
+	var err error
+	maxEmailRetries := 3
+	isEmailSent := false
+	for i := 0; i < maxEmailRetries; i++ {
 
-			jobFailure := VerificationMailJobFailureDetail{
-				UserId:    verificationMailJob.UserId,
-				UserEmail: verificationMailJob.ToEmail,
-				TimeStamp: time.Now(),
-				Job:       verificationMailJob,
-			}
+		if err = sendMail(mailer, mailJob); err != nil {
+			log.Printf("Error sending %s mail, attempt %d : %v\n", mailJob.Type, i+1, err)
+			continue
+		}
+		isEmailSent = true
+		break
+	}
 
-			jobFailureJson, _ := json.Marshal(jobFailure)
-			_ = rdb.LPush(context.Background(), "queue:email:dlq", string(jobFailureJson)).Err()
+	if !isEmailSent {
 
-			continue
+		log.Printf("Error sending %s mail, attempt %d : %v\n", mailJob.Type, maxEmailRetries, err)
+
+		type MailJobFailureDetail struct {
+			UserId    int       `json:"user_id"`
+			UserEmail string    `json:"user_email"`
+			TimeStamp time.Time `json:"timestamp"`
+			Job       MailJob   `json:"job"`
+		}
+
+		jobFailure := MailJobFailureDetail{
+			UserId:    mailJob.UserId,
+			UserEmail: mailJob.ToEmail,
+			TimeStamp: time.Now(),
+			Job:       mailJob,
 		}
 
-		log.Printf("Email sent successfully to %s\n", verificationMailJob.ToEmail)
+		jobFailureJson, _ := json.Marshal(jobFailure)
+		_ = rdb.LPush(context.Background(), "queue:email:dlq", string(jobFailureJson)).Err()
+
+		return
+	}
+
+	log.Printf("Email sent successfully to %s\n", mailJob.ToEmail)
+}
+
+// MailJob is a job popped off the email queue, jobs without a type are verification mails
+type MailJob struct {
+	Type              string `json:"type"`
+	FromEmail         string `json:"from_email"`
+	ToEmail           string `json:"to_email"`
+	UserId            int    `json:"user_id"`
+	Subject           string `json:"subject"`
+	EmailTemplatePath string `json:"email_template_path"`
+	Token             string `json:"token"`
+}
+
+func sendMail(m *mailer.Mailer, job MailJob) error {
+
+	switch job.Type {
+	case mailer.MailJobTypePasswordReset:
+		return m.SendPasswordResetMail(job.FromEmail, job.ToEmail, job.Subject, job.Token, job.EmailTemplatePath)
+	default:
+		return m.SendVerificationMail(job.FromEmail, job.ToEmail, job.Subject, job.Token, job.EmailTemplatePath)
 	}
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// S3CleanupJob removes every object under Prefix except KeepKeys (uploads that are still referenced),
+// or only Keys (uploads of a deleted post) when they are set
+type S3CleanupJob struct {
+	UserId   int      `json:"user_id"`
+	Prefix   string   `json:"prefix"`
+	KeepKeys []string `json:"keep_keys"`
+	Keys     []string `json:"keys,omitempty"`
+}
+
+func processS3CleanupJob(rdb *goredis.Client, s3Client *s3.Client, bucket string, s3CleanupJobStr string) {
+
+	var s3CleanupJob S3CleanupJob
+	if err := json.Unmarshal([]byte(s3CleanupJobStr), &s3CleanupJob); err != nil {
+		log.Printf("Error unmarshalling s3 cleanup job: %v\n", err)
+		return
+	}
+
+	var deletedCount int
+	var err error
+
+	if len(s3CleanupJob.Keys) > 0 {
+		deletedCount, err = deleteS3Keys(s3Client, bucket, s3CleanupJob.Prefix, s3CleanupJob.Keys)
+	} else {
+		deletedCount, err = deleteS3Prefix(s3Client, bucket, s3CleanupJob.Prefix, s3CleanupJob.KeepKeys)
+	}
+	if err != nil {
+
+		log.Printf("Error cleaning up s3 prefix %s: %v\n", s3CleanupJob.Prefix, err)
+
+		type S3CleanupJobFailureDetail struct {
+			UserId    int          `json:"user_id"`
+			TimeStamp time.Time    `json:"timestamp"`
+			Job       S3CleanupJob `json:"job"`
+		}
+
+		jobFailureJson, _ := json.Marshal(S3CleanupJobFailureDetail{UserId: s3CleanupJob.UserId, TimeStamp: time.Now(), Job: s3CleanupJob})
+		_ = rdb.LPush(context.Background(), "queue:s3:cleanup:dlq", string(jobFailureJson)).Err()
+
+		return
+	}
+
+	log.Printf("Deleted %d objects under %s\n", deletedCount, s3CleanupJob.Prefix)
+}
+
+func deleteS3Prefix(s3Client *s3.Client, bucket string, prefix string, keepKeys []string) (int, error) {
+
+	ctx := context.Background()
+	deletedCount := 0
+
+	keep := make(map[string]bool, len(keepKeys))
+	for _, key := range keepKeys {
+		keep[key] = true
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return deletedCount, err
+		}
+
+		var objects []types.ObjectIdentifier
+		for _, object := range page.Contents {
+			if keep[aws.ToString(object.Key)] {
+				continue
+			}
+			objects = append(objects, types.ObjectIdentifier{Key: object.Key})
+		}
+
+		if len(objects) == 0 {
+			continue
+		}
+
+		// a list page holds at most 1000 keys, which is also the DeleteObjects limit
+		_, err = s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deletedCount, err
+		}
+
+		deletedCount += len(objects)
+	}
+
+	return deletedCount, nil
+}
+
+// deleteS3Keys deletes the given keys, skipping any that aren't under prefix
+func deleteS3Keys(s3Client *s3.Client, bucket string, prefix string, keys []string) (int, error) {
+
+	var objects []types.ObjectIdentifier
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+	}
+
+	// DeleteObjects takes at most 1000 keys
+	for start := 0; start < len(objects); start += 1000 {
+
+		end := min(start+1000, len(objects))
+
+		_, err := s3Client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: objects[start:end], Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return start, err
+		}
+	}
+
+	return len(objects), nil
+}
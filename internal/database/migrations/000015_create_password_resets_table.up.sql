@@ -0,0 +1,13 @@
+
+
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS password_resets(
+    token TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expiration TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
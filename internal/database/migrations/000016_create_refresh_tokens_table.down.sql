@@ -0,0 +1,4 @@
+
+
+
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,15 @@
+
+
+
+CREATE TABLE IF NOT EXISTS refresh_tokens(
+    token TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    family_id TEXT NOT NULL,
+    expiration TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens(family_id);
@@ -0,0 +1,4 @@
+
+
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
@@ -0,0 +1,6 @@
+
+
+
+ALTER TABLE post_bookmarks DROP COLUMN IF EXISTS collection_id;
+
+DROP TABLE IF EXISTS bookmark_collections;
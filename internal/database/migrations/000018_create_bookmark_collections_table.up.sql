@@ -0,0 +1,15 @@
+
+
+
+CREATE TABLE IF NOT EXISTS bookmark_collections(
+    id SERIAL PRIMARY KEY,
+    collection_name TEXT NOT NULL,
+    collection_owner_id INTEGER NOT NULL,
+    collection_created_at TIMESTAMP DEFAULT NOW(),
+    collection_updated_at TIMESTAMP,
+    FOREIGN KEY(collection_owner_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(collection_owner_id,collection_name)
+);
+
+-- a bookmark belongs to at most one collection, deleting a collection leaves its bookmarks unfiled
+ALTER TABLE post_bookmarks ADD COLUMN IF NOT EXISTS collection_id INTEGER REFERENCES bookmark_collections(id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+
+
+
+ALTER TABLE user_communities DROP COLUMN IF EXISTS role;
+
+DROP TYPE IF EXISTS community_role;
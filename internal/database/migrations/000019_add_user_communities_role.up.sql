@@ -0,0 +1,7 @@
+
+
+
+CREATE TYPE community_role AS ENUM('member','moderator');
+
+-- the owner of a community is communities.community_owner_id, members and moderators are rows of user_communities
+ALTER TABLE user_communities ADD COLUMN IF NOT EXISTS role community_role NOT NULL DEFAULT 'member';
@@ -0,0 +1,4 @@
+
+
+
+DROP TABLE IF EXISTS community_ownership_transfers;
@@ -0,0 +1,14 @@
+
+
+
+-- a community has at most one pending ownership transfer, it takes effect once the target user accepts it
+CREATE TABLE IF NOT EXISTS community_ownership_transfers(
+    community_id INTEGER PRIMARY KEY,
+    from_user_id INTEGER NOT NULL,
+    to_user_id INTEGER NOT NULL,
+    expiration TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(community_id) REFERENCES communities(id) ON DELETE CASCADE,
+    FOREIGN KEY(from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+
+
+
+DROP TABLE IF EXISTS community_invites;
+
+DROP TABLE IF EXISTS community_join_requests;
+
+ALTER TABLE communities DROP COLUMN IF EXISTS community_visibility;
+
+DROP TYPE IF EXISTS community_visibility;
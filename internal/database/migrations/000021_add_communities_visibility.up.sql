@@ -0,0 +1,30 @@
+
+
+
+CREATE TYPE community_visibility AS ENUM('public','restricted','private');
+
+ALTER TABLE communities ADD COLUMN IF NOT EXISTS community_visibility community_visibility NOT NULL DEFAULT 'public';
+
+-- pending requests to join a restricted or private community, removed once a moderator approves or rejects them
+CREATE TABLE IF NOT EXISTS community_join_requests(
+    user_id INTEGER NOT NULL,
+    community_id INTEGER NOT NULL,
+    requested_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(community_id) REFERENCES communities(id) ON DELETE CASCADE,
+    UNIQUE(user_id,community_id)
+);
+
+-- invite links let users join without approval, max_uses and expiration are optional
+CREATE TABLE IF NOT EXISTS community_invites(
+    id SERIAL PRIMARY KEY,
+    invite_code TEXT UNIQUE NOT NULL,
+    community_id INTEGER NOT NULL,
+    created_by_id INTEGER NOT NULL,
+    max_uses INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expiration TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(community_id) REFERENCES communities(id) ON DELETE CASCADE,
+    FOREIGN KEY(created_by_id) REFERENCES users(id) ON DELETE CASCADE
+);
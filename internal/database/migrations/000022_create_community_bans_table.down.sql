@@ -0,0 +1,6 @@
+
+
+
+DROP TABLE IF EXISTS community_bans;
+
+DROP TYPE IF EXISTS community_ban_type;
@@ -0,0 +1,20 @@
+
+
+
+CREATE TYPE community_ban_type AS ENUM('ban','mute');
+
+-- a ban keeps the user out of the community entirely, a mute only stops them from posting and commenting.
+-- expiration is optional, bans without one are permanent
+CREATE TABLE IF NOT EXISTS community_bans(
+    community_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    ban_type community_ban_type NOT NULL DEFAULT 'ban',
+    ban_reason TEXT NOT NULL,
+    banned_by_id INTEGER,
+    expiration TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(community_id) REFERENCES communities(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(banned_by_id) REFERENCES users(id) ON DELETE SET NULL,
+    PRIMARY KEY(community_id,user_id)
+);
@@ -0,0 +1,10 @@
+
+
+
+DROP TABLE IF EXISTS reports;
+
+DROP TYPE IF EXISTS report_resolution;
+
+DROP TYPE IF EXISTS report_status;
+
+DROP TYPE IF EXISTS report_reason;
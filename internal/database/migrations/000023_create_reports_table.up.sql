@@ -0,0 +1,32 @@
+
+
+
+CREATE TYPE report_reason AS ENUM('spam','harassment','hate_speech','violence','sexual_content','misinformation','other');
+
+CREATE TYPE report_status AS ENUM('open','resolved');
+
+CREATE TYPE report_resolution AS ENUM('content_removed','dismissed','author_banned');
+
+-- a report flags either a post or a comment. the content references are nulled when it's deleted
+-- so resolved reports stay around as a record of what was done
+CREATE TABLE IF NOT EXISTS reports(
+    id SERIAL PRIMARY KEY,
+    reporter_id INTEGER NOT NULL,
+    community_id INTEGER NOT NULL,
+    post_id INTEGER,
+    comment_id INTEGER,
+    report_reason report_reason NOT NULL,
+    report_details TEXT NOT NULL DEFAULT '',
+    report_status report_status NOT NULL DEFAULT 'open',
+    report_resolution report_resolution,
+    resolved_by_id INTEGER,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(community_id) REFERENCES communities(id) ON DELETE CASCADE,
+    FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE SET NULL,
+    FOREIGN KEY(comment_id) REFERENCES post_comments(id) ON DELETE SET NULL,
+    FOREIGN KEY(resolved_by_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS reports_community_id_status_idx ON reports(community_id, report_status);
@@ -0,0 +1,8 @@
+
+
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+
+DROP FUNCTION IF EXISTS reject_audit_log_changes;
+
+DROP TABLE IF EXISTS audit_logs;
@@ -0,0 +1,30 @@
+
+
+
+-- append-only record of privileged actions. actor_id and community_id are not foreign keys
+-- so entries outlive the users and communities they mention
+CREATE TABLE IF NOT EXISTS audit_logs(
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL,
+    community_id INTEGER,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    before_snapshot JSONB,
+    after_snapshot JSONB,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_logs_community_id_created_at_idx ON audit_logs(community_id, created_at);
+
+CREATE INDEX IF NOT EXISTS audit_logs_actor_id_created_at_idx ON audit_logs(actor_id, created_at);
+
+CREATE OR REPLACE FUNCTION reject_audit_log_changes() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+FOR EACH ROW EXECUTE FUNCTION reject_audit_log_changes();
@@ -0,0 +1,4 @@
+
+
+
+DROP TABLE IF EXISTS post_revisions;
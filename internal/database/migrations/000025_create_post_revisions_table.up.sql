@@ -0,0 +1,17 @@
+
+
+
+-- every edit of a post stores the version it replaced, revision_number counts up from 1 per post
+CREATE TABLE IF NOT EXISTS post_revisions(
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    revision_number INTEGER NOT NULL,
+    post_title TEXT NOT NULL,
+    post_content TEXT NOT NULL,
+    post_image_urls TEXT[] NOT NULL DEFAULT '{}',
+    edited_by_id INTEGER,
+    revision_created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY(edited_by_id) REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE(post_id,revision_number)
+);
@@ -0,0 +1,6 @@
+
+
+
+DROP TABLE IF EXISTS comment_revisions;
+
+ALTER TABLE post_comments DROP COLUMN IF EXISTS comment_deleted_at;
@@ -0,0 +1,18 @@
+
+
+
+-- deleted comments keep their row so replies under them stay in the thread
+ALTER TABLE post_comments ADD COLUMN IF NOT EXISTS comment_deleted_at TIMESTAMP;
+
+-- every edit of a comment stores the content it replaced, revision_number counts up from 1 per comment
+CREATE TABLE IF NOT EXISTS comment_revisions(
+    id SERIAL PRIMARY KEY,
+    comment_id INTEGER NOT NULL,
+    revision_number INTEGER NOT NULL,
+    comment_content TEXT NOT NULL,
+    edited_by_id INTEGER,
+    revision_created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(comment_id) REFERENCES post_comments(id) ON DELETE CASCADE,
+    FOREIGN KEY(edited_by_id) REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE(comment_id,revision_number)
+);
@@ -0,0 +1,31 @@
+
+
+
+CREATE TABLE IF NOT EXISTS post_likes(
+    liked_by_id INTEGER NOT NULL,
+    liked_post_id INTEGER NOT NULL,
+    liked_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(liked_by_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(liked_post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    UNIQUE(liked_by_id,liked_post_id)
+);
+
+CREATE TABLE IF NOT EXISTS post_comment_likes(
+    liked_by_id INTEGER NOT NULL,
+    liked_post_comment_id INTEGER NOT NULL,
+    liked_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(liked_by_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(liked_post_comment_id) REFERENCES post_comments(id) ON DELETE CASCADE,
+    UNIQUE(liked_by_id,liked_post_comment_id)
+);
+
+-- upvotes go back to being likes, downvotes are lost
+INSERT INTO post_likes(liked_by_id, liked_post_id, liked_at)
+SELECT voter_id, post_id, voted_at FROM post_votes WHERE vote_value = 1;
+
+INSERT INTO post_comment_likes(liked_by_id, liked_post_comment_id, liked_at)
+SELECT voter_id, comment_id, voted_at FROM post_comment_votes WHERE vote_value = 1;
+
+DROP TABLE IF EXISTS post_comment_votes;
+
+DROP TABLE IF EXISTS post_votes;
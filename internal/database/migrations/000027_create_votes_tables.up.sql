@@ -0,0 +1,40 @@
+
+
+
+-- votes replace likes, vote_value is +1 for an upvote and -1 for a downvote
+CREATE TABLE IF NOT EXISTS post_votes(
+    voter_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    vote_value SMALLINT NOT NULL CHECK(vote_value IN (-1, 1)),
+    voted_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(voter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    PRIMARY KEY(voter_id,post_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_votes_post_id_idx ON post_votes(post_id);
+
+CREATE TABLE IF NOT EXISTS post_comment_votes(
+    voter_id INTEGER NOT NULL,
+    comment_id INTEGER NOT NULL,
+    vote_value SMALLINT NOT NULL CHECK(vote_value IN (-1, 1)),
+    voted_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(voter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(comment_id) REFERENCES post_comments(id) ON DELETE CASCADE,
+    PRIMARY KEY(voter_id,comment_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_comment_votes_comment_id_idx ON post_comment_votes(comment_id);
+
+-- every existing like becomes an upvote
+INSERT INTO post_votes(voter_id, post_id, vote_value, voted_at)
+SELECT liked_by_id, liked_post_id, 1, liked_at FROM post_likes
+ON CONFLICT DO NOTHING;
+
+INSERT INTO post_comment_votes(voter_id, comment_id, vote_value, voted_at)
+SELECT liked_by_id, liked_post_comment_id, 1, liked_at FROM post_comment_likes
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS post_likes;
+
+DROP TABLE IF EXISTS post_comment_likes;
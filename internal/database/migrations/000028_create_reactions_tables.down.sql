@@ -0,0 +1,8 @@
+
+
+
+DROP TABLE IF EXISTS post_comment_reactions;
+
+DROP TABLE IF EXISTS post_reactions;
+
+ALTER TABLE communities DROP COLUMN IF EXISTS allowed_reactions;
@@ -0,0 +1,29 @@
+
+
+
+-- the reactions allowed in a community, NULL allows the default set
+ALTER TABLE communities ADD COLUMN IF NOT EXISTS allowed_reactions TEXT[];
+
+CREATE TABLE IF NOT EXISTS post_reactions(
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    reaction TEXT NOT NULL,
+    reacted_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    PRIMARY KEY(user_id,post_id,reaction)
+);
+
+CREATE INDEX IF NOT EXISTS post_reactions_post_id_idx ON post_reactions(post_id);
+
+CREATE TABLE IF NOT EXISTS post_comment_reactions(
+    user_id INTEGER NOT NULL,
+    comment_id INTEGER NOT NULL,
+    reaction TEXT NOT NULL,
+    reacted_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(comment_id) REFERENCES post_comments(id) ON DELETE CASCADE,
+    PRIMARY KEY(user_id,comment_id,reaction)
+);
+
+CREATE INDEX IF NOT EXISTS post_comment_reactions_comment_id_idx ON post_comment_reactions(comment_id);
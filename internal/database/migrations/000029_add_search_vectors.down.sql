@@ -0,0 +1,14 @@
+
+
+
+DROP INDEX IF EXISTS communities_search_vector_idx;
+
+ALTER TABLE communities DROP COLUMN IF EXISTS community_search_vector;
+
+DROP INDEX IF EXISTS post_comments_search_vector_idx;
+
+ALTER TABLE post_comments DROP COLUMN IF EXISTS comment_search_vector;
+
+DROP INDEX IF EXISTS posts_search_vector_idx;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS post_search_vector;
@@ -0,0 +1,23 @@
+
+
+
+-- titles and names rank above content and descriptions
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS post_search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(post_title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(post_content, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS posts_search_vector_idx ON posts USING GIN(post_search_vector);
+
+ALTER TABLE post_comments ADD COLUMN IF NOT EXISTS comment_search_vector TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('english', COALESCE(comment_content, ''))
+) STORED;
+
+CREATE INDEX IF NOT EXISTS post_comments_search_vector_idx ON post_comments USING GIN(comment_search_vector);
+
+ALTER TABLE communities ADD COLUMN IF NOT EXISTS community_search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(community_name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(community_description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS communities_search_vector_idx ON communities USING GIN(community_search_vector);
@@ -0,0 +1,18 @@
+
+
+
+DROP INDEX IF EXISTS posts_post_owner_id_idx;
+
+DROP INDEX IF EXISTS user_topic_preferences_topic_id_idx;
+
+DROP INDEX IF EXISTS community_topics_topic_id_idx;
+
+DROP INDEX IF EXISTS user_communities_community_id_idx;
+
+DROP INDEX IF EXISTS users_username_trgm_idx;
+
+DROP INDEX IF EXISTS topics_topic_name_trgm_idx;
+
+DROP INDEX IF EXISTS communities_community_name_trgm_idx;
+
+DROP EXTENSION IF EXISTS pg_trgm;
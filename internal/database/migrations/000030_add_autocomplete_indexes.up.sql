@@ -0,0 +1,20 @@
+
+
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- trigram indexes serve the case insensitive prefix matches of autocomplete
+CREATE INDEX IF NOT EXISTS communities_community_name_trgm_idx ON communities USING GIN(LOWER(community_name) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS topics_topic_name_trgm_idx ON topics USING GIN(LOWER(topic_name) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_username_trgm_idx ON users USING GIN(LOWER(username) gin_trgm_ops);
+
+-- popularity counts of the matches
+CREATE INDEX IF NOT EXISTS user_communities_community_id_idx ON user_communities(community_id);
+
+CREATE INDEX IF NOT EXISTS community_topics_topic_id_idx ON community_topics(topic_id);
+
+CREATE INDEX IF NOT EXISTS user_topic_preferences_topic_id_idx ON user_topic_preferences(topic_id);
+
+CREATE INDEX IF NOT EXISTS posts_post_owner_id_idx ON posts(post_owner_id);
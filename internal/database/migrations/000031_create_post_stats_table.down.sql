@@ -0,0 +1,22 @@
+
+
+
+DROP TRIGGER IF EXISTS post_bookmarks_update_post_stats ON post_bookmarks;
+
+DROP FUNCTION IF EXISTS update_post_stats_bookmarks();
+
+DROP TRIGGER IF EXISTS post_comments_update_post_stats ON post_comments;
+
+DROP FUNCTION IF EXISTS update_post_stats_comments();
+
+DROP TRIGGER IF EXISTS post_votes_update_post_stats ON post_votes;
+
+DROP FUNCTION IF EXISTS update_post_stats_votes();
+
+DROP TRIGGER IF EXISTS posts_create_post_stats ON posts;
+
+DROP FUNCTION IF EXISTS create_post_stats();
+
+DROP INDEX IF EXISTS posts_post_created_at_idx;
+
+DROP TABLE IF EXISTS post_stats;
@@ -0,0 +1,97 @@
+
+
+
+-- counters of each post kept up to date by triggers, comments_count only counts top level comments
+-- like the feeds always have. hot_score is recomputed periodically by the worker
+CREATE TABLE IF NOT EXISTS post_stats(
+    post_id INTEGER PRIMARY KEY,
+    upvotes_count INTEGER NOT NULL DEFAULT 0,
+    downvotes_count INTEGER NOT NULL DEFAULT 0,
+    score INTEGER GENERATED ALWAYS AS (upvotes_count - downvotes_count) STORED,
+    comments_count INTEGER NOT NULL DEFAULT 0,
+    bookmarks_count INTEGER NOT NULL DEFAULT 0,
+    hot_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    hot_score_updated_at TIMESTAMP,
+    FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS post_stats_score_idx ON post_stats(score DESC, post_id DESC);
+
+CREATE INDEX IF NOT EXISTS post_stats_hot_score_idx ON post_stats(hot_score DESC, post_id DESC);
+
+CREATE INDEX IF NOT EXISTS post_stats_hot_score_updated_at_idx ON post_stats(hot_score_updated_at);
+
+CREATE INDEX IF NOT EXISTS posts_post_created_at_idx ON posts(post_created_at DESC, id DESC);
+
+INSERT INTO post_stats(post_id, upvotes_count, downvotes_count, comments_count, bookmarks_count)
+SELECT p.id,
+    (SELECT COUNT(*) FROM post_votes AS pv WHERE pv.post_id = p.id AND pv.vote_value = 1),
+    (SELECT COUNT(*) FROM post_votes AS pv WHERE pv.post_id = p.id AND pv.vote_value = -1),
+    (SELECT COUNT(*) FROM post_comments AS pc WHERE pc.post_id = p.id AND pc.parent_comment_id IS NULL),
+    (SELECT COUNT(*) FROM post_bookmarks AS pb WHERE pb.bookmarked_post_id = p.id)
+FROM posts AS p
+ON CONFLICT (post_id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION create_post_stats() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO post_stats(post_id) VALUES(NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_create_post_stats AFTER INSERT ON posts
+FOR EACH ROW EXECUTE FUNCTION create_post_stats();
+
+-- a changed vote moves the post from one counter to the other
+CREATE OR REPLACE FUNCTION update_post_stats_votes() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE post_stats SET
+            upvotes_count = upvotes_count - (OLD.vote_value = 1)::INTEGER,
+            downvotes_count = downvotes_count - (OLD.vote_value = -1)::INTEGER
+        WHERE post_id = OLD.post_id;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE post_stats SET
+            upvotes_count = upvotes_count + (NEW.vote_value = 1)::INTEGER,
+            downvotes_count = downvotes_count + (NEW.vote_value = -1)::INTEGER
+        WHERE post_id = NEW.post_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_votes_update_post_stats AFTER INSERT OR UPDATE OR DELETE ON post_votes
+FOR EACH ROW EXECUTE FUNCTION update_post_stats_votes();
+
+CREATE OR REPLACE FUNCTION update_post_stats_comments() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.parent_comment_id IS NULL THEN
+        UPDATE post_stats SET comments_count = comments_count + 1 WHERE post_id = NEW.post_id;
+    ELSIF TG_OP = 'DELETE' AND OLD.parent_comment_id IS NULL THEN
+        UPDATE post_stats SET comments_count = comments_count - 1 WHERE post_id = OLD.post_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_comments_update_post_stats AFTER INSERT OR DELETE ON post_comments
+FOR EACH ROW EXECUTE FUNCTION update_post_stats_comments();
+
+CREATE OR REPLACE FUNCTION update_post_stats_bookmarks() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE post_stats SET bookmarks_count = bookmarks_count + 1 WHERE post_id = NEW.bookmarked_post_id;
+    ELSE
+        UPDATE post_stats SET bookmarks_count = bookmarks_count - 1 WHERE post_id = OLD.bookmarked_post_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_bookmarks_update_post_stats AFTER INSERT OR DELETE ON post_bookmarks
+FOR EACH ROW EXECUTE FUNCTION update_post_stats_bookmarks();
@@ -0,0 +1,9 @@
+
+
+
+CREATE OR REPLACE FUNCTION create_post_stats() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO post_stats(post_id) VALUES(NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,20 @@
+
+
+
+-- posts inserted before the worker's first run start with their hot score as of now
+UPDATE post_stats AS ps SET hot_score = (CASE WHEN p.post_created_at > NOW()::timestamp - INTERVAL '7 days'
+    THEN (0.3 * ps.score + 0.5 * ps.comments_count + 0.2 * ps.bookmarks_count) /
+    POWER(GREATEST(EXTRACT(EPOCH FROM (NOW()::timestamp - p.post_created_at)) / 60, 1), 2)
+    ELSE 0 END)::float8, hot_score_updated_at = NOW()::timestamp
+FROM posts AS p
+WHERE p.id = ps.post_id AND ps.hot_score_updated_at IS NULL;
+
+-- a new post has no votes, comments or bookmarks so its hot score is 0, it joins the hot scores the
+-- worker last computed instead of waiting for the next run
+CREATE OR REPLACE FUNCTION create_post_stats() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO post_stats(post_id, hot_score, hot_score_updated_at)
+    SELECT NEW.id, 0, MAX(hot_score_updated_at) FROM post_stats;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
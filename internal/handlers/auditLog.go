@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const MAX_AUDIT_LOGS_LIMIT = 100
+
+// moderator only, the community's audit log. filtered by ?actorId, ?action and a ?from/?to time range (RFC3339)
+func (h *Handler) GetCommunityAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	h.getAuditLogs(w, r, &communityId)
+}
+
+// admin only, the site-wide audit log. takes the community filters along with ?communityId
+func (h *Handler) GetAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+
+	var communityId *int
+
+	if r.URL.Query().Get("communityId") != "" {
+		id, err := strconv.Atoi(r.URL.Query().Get("communityId"))
+		if err != nil {
+			writeJSONError(w, "invalid query param communityId", http.StatusBadRequest)
+			return
+		}
+		communityId = &id
+	}
+
+	h.getAuditLogs(w, r, communityId)
+}
+
+func (h *Handler) getAuditLogs(w http.ResponseWriter, r *http.Request, communityId *int) {
+
+	page, limit, err := parsePagination(r, 10, MAX_AUDIT_LOGS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := storage.AuditLogFilter{
+		CommunityId: communityId,
+		Action:      storage.AuditAction(r.URL.Query().Get("action")),
+	}
+
+	if r.URL.Query().Get("actorId") != "" {
+		actorId, err := strconv.Atoi(r.URL.Query().Get("actorId"))
+		if err != nil {
+			writeJSONError(w, "invalid query param actorId", http.StatusBadRequest)
+			return
+		}
+		filter.ActorId = &actorId
+	}
+
+	if r.URL.Query().Get("from") != "" {
+		from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+		if err != nil {
+			writeJSONError(w, "invalid query param from", http.StatusBadRequest)
+			return
+		}
+		filter.From = &from
+	}
+
+	if r.URL.Query().Get("to") != "" {
+		to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+		if err != nil {
+			writeJSONError(w, "invalid query param to", http.StatusBadRequest)
+			return
+		}
+		filter.To = &to
+	}
+
+	skip := page*limit - limit
+
+	auditLogs, err := h.storage.AuditLogs.GetAuditLogs(filter, skip, limit)
+	if err != nil {
+		log.Printf("failed to get audit logs: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalAuditLogsCount, err := h.storage.AuditLogs.GetAuditLogsCount(filter)
+	if err != nil {
+		log.Printf("failed to get audit logs count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalAuditLogsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool               `json:"success"`
+		AuditLogs []storage.AuditLog `json:"audit_logs"`
+		NoOfPages int                `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, AuditLogs: auditLogs, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -8,12 +8,14 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/dhruv15803/go-community-platform/internal/mailer"
 	"github.com/dhruv15803/go-community-platform/internal/storage"
 	"github.com/dhruv15803/go-community-platform/internal/utils"
 	"github.com/go-chi/chi/v5"
@@ -31,11 +33,35 @@ type LoginUserRequest struct {
 	Password string `json:"password"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
 var (
 	JWT_SECRET = []byte(os.Getenv("JWT_SECRET"))
 	AuthUserId = "AuthUserId"
 )
 
+const (
+	ACCESS_TOKEN_TTL  = time.Minute * 15
+	REFRESH_TOKEN_TTL = time.Hour * 24 * 30
+
+	USER_INVITATION_TTL = time.Minute * 30
+
+	// resending a verification mail is limited per email address
+	RESEND_VERIFICATION_COOLDOWN     = time.Minute
+	RESEND_VERIFICATION_WINDOW       = time.Hour
+	RESEND_VERIFICATION_WINDOW_LIMIT = 5
+)
+
 func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	var registerUserPayload RegisterUserRequest
@@ -90,7 +116,7 @@ func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 	hashedToken := hashPlainTextToken(plainTextToken)
 
 	//	create user and user invitation
-	invitationExpirationTime := time.Now().Add(time.Minute * 30)
+	invitationExpirationTime := time.Now().Add(USER_INVITATION_TTL)
 	user, err := h.storage.Users.CreateUserAndInvitation(userEmail, hashedPassword, hashedToken, invitationExpirationTime)
 	if err != nil {
 		log.Printf("failed createUserAndInvitation: %v\n", err)
@@ -99,6 +125,7 @@ func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type VerificationMailJob struct {
+		Type              string `json:"type"`
 		FromEmail         string `json:"from_email"`
 		ToEmail           string `json:"to_email"`
 		UserId            int    `json:"user_id"`
@@ -108,6 +135,7 @@ func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	verificationMailJob := VerificationMailJob{
+		Type:              mailer.MailJobTypeVerification,
 		FromEmail:         os.Getenv("MAILER_USERNAME"),
 		ToEmail:           user.Email,
 		UserId:            user.Id,
@@ -205,38 +233,19 @@ func (h *Handler) ActivateUserHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	claims := jwt.MapClaims{
-		"sub": updatedUser.Id,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString(JWT_SECRET)
+	sessionId, err := h.createSession(r, updatedUser.Id)
 	if err != nil {
+		log.Printf("failed to create session: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var sameSiteConfig http.SameSite
-
-	if os.Getenv("GO_ENV") == "production" {
-		sameSiteConfig = http.SameSiteNoneMode
-	} else {
-		sameSiteConfig = http.SameSiteLaxMode
-	}
-
-	cookie := http.Cookie{
-		Name:     "auth_token",
-		Value:    tokenStr,
-		HttpOnly: true,
-		Secure:   os.Getenv("GO_ENV") == "production",
-		SameSite: sameSiteConfig,
-		Path:     "/",
-		MaxAge:   60 * 60 * 24,
+	if err := h.issueAuthTokens(w, updatedUser.Id, sessionId); err != nil {
+		log.Printf("failed to issue auth tokens: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	http.SetCookie(w, &cookie)
-
 	type Response struct {
 		Success bool         `json:"success"`
 		Message string       `json:"message"`
@@ -284,38 +293,19 @@ func (h *Handler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	//  email and password correct
 
-	claims := jwt.MapClaims{
-		"sub": user.Id,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString(JWT_SECRET)
+	sessionId, err := h.createSession(r, user.Id)
 	if err != nil {
+		log.Printf("failed to create session: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var sameSiteConfig http.SameSite
-
-	if os.Getenv("GO_ENV") == "production" {
-		sameSiteConfig = http.SameSiteNoneMode
-	} else {
-		sameSiteConfig = http.SameSiteLaxMode
-	}
-
-	cookie := http.Cookie{
-		Name:     "auth_token",
-		Value:    tokenStr,
-		HttpOnly: true,
-		Secure:   os.Getenv("GO_ENV") == "production",
-		SameSite: sameSiteConfig,
-		Path:     "/",
-		MaxAge:   60 * 60 * 24,
+	if err := h.issueAuthTokens(w, user.Id, sessionId); err != nil {
+		log.Printf("failed to issue auth tokens: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	http.SetCookie(w, &cookie)
-
 	type Response struct {
 		Success bool         `json:"success"`
 		Message string       `json:"message"`
@@ -328,48 +318,126 @@ func (h *Handler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
+// authError is an auth token or session that failed validation, along with how AuthMiddleware responds to it
+type authError struct {
+	status  int
+	message string
+}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func (e *authError) Error() string {
+	return e.message
+}
 
-		cookie, err := r.Cookie("auth_token")
-		if err != nil {
-			writeJSONError(w, "auth token not found", http.StatusBadRequest)
-			return
+// authenticateRequest validates the auth token cookie of the request and its session, returning the
+// authenticated user and session. a token that fails validation is an *authError
+func (h *Handler) authenticateRequest(r *http.Request) (int, string, error) {
+
+	cookie, err := r.Cookie("auth_token")
+	if err != nil {
+		return 0, "", &authError{status: http.StatusBadRequest, message: "auth token not found"}
+	}
+
+	tokenStr := cookie.Value
+
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
+
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
 		}
 
-		tokenStr := cookie.Value
+		return JWT_SECRET, nil
+	})
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			// access tokens are short lived, the client should call /api/auth/refresh
+			return 0, "", &authError{status: http.StatusUnauthorized, message: "auth token expired"}
+		}
+		log.Printf("failed to parse token:- %v\n", err)
+		return 0, "", &authError{status: http.StatusBadRequest, message: "invalid token"}
+	}
 
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, "", &authError{status: http.StatusBadRequest, message: "invalid token"}
+	}
+
+	expirationTimeFloat := claims["exp"].(float64)
+	expirationTime := int(expirationTimeFloat)
+	userIdFloat := claims["sub"].(float64)
+	userId := int(userIdFloat)
+
+	if time.Now().Unix() > int64(expirationTime) {
+		return 0, "", &authError{status: http.StatusBadRequest, message: "auth token expired"}
+	}
+
+	sessionId, ok := claims["sid"].(string)
+	if !ok {
+		return 0, "", &authError{status: http.StatusBadRequest, message: "invalid token"}
+	}
+
+	// the token is only valid as long as its session has not been revoked
+	session, err := h.getSession(sessionId)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if session == nil || session.UserId != userId {
+		return 0, "", &authError{status: http.StatusUnauthorized, message: "session expired or revoked"}
+	}
+
+	if err := h.touchSession(sessionId); err != nil {
+		log.Printf("failed to update session last seen: %v\n", err)
+	}
+
+	return userId, sessionId, nil
+}
+
+func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 
-			return JWT_SECRET, nil
-		})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
+		userId, sessionId, err := h.authenticateRequest(r)
 		if err != nil {
-			log.Printf("failed to parse token:- %v\n", err)
-			writeJSONError(w, "invalid token", http.StatusBadRequest)
+			var authErr *authError
+			if errors.As(err, &authErr) {
+				writeJSONError(w, authErr.message, authErr.status)
+				return
+			}
+			log.Printf("failed to get session: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		ctx := context.WithValue(r.Context(), AuthUserId, userId)
+		ctx = context.WithValue(ctx, AuthSessionId, sessionId)
+		r = r.WithContext(ctx)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// OptionalAuthMiddleware authenticates requests that carry a valid auth token and lets every other request
+// through anonymously, so an expired or revoked token doesn't lock a client out of public endpoints.
+// handlers read a user id of 0 for anonymous requests
+func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 
-			expirationTimeFloat := claims["exp"].(float64)
-			expirationTime := int(expirationTimeFloat)
-			userIdFloat := claims["sub"].(float64)
-			userId := int(userIdFloat)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-			if time.Now().Unix() > int64(expirationTime) {
-				writeJSONError(w, "auth token expired", http.StatusBadRequest)
+		userId, sessionId, err := h.authenticateRequest(r)
+		if err != nil {
+			var authErr *authError
+			if errors.As(err, &authErr) {
+				next.ServeHTTP(w, r)
 				return
 			}
-
-			ctx := context.WithValue(r.Context(), AuthUserId, userId)
-			r = r.WithContext(ctx)
-			next.ServeHTTP(w, r)
+			log.Printf("failed to get session: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
 		}
+
+		ctx := context.WithValue(r.Context(), AuthUserId, userId)
+		ctx = context.WithValue(ctx, AuthSessionId, sessionId)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -458,36 +526,484 @@ func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// user entry exists
+	// user entry exists, revoke the current session and its refresh tokens so neither can be reused
+
+	sessionId, ok := r.Context().Value(AuthSessionId).(string)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.revokeRefreshTokenFamily(userId, sessionId); err != nil {
+		log.Printf("failed to revoke session: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	clearAuthCookies(w)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "logged out successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// always responds with the same message so that registered emails cannot be enumerated
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+
+	var forgotPasswordPayload ForgotPasswordRequest
+
+	if err := readJSON(r, &forgotPasswordPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userEmail := strings.ToLower(strings.TrimSpace(forgotPasswordPayload.Email))
+
+	if userEmail == "" {
+		writeJSONError(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	response := Response{Success: true, Message: "if an account exists with this email, a password reset link has been sent"}
+
+	user, err := h.storage.Users.GetVerifiedUserByEmail(userEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := writeJSON(w, response, http.StatusOK); err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		} else {
+			log.Printf("failed query GetVerifiedUserByEmail: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	plainTextToken := generateToken(32)
+	hashedToken := hashPlainTextToken(plainTextToken)
+
+	passwordResetExpirationTime := time.Now().Add(time.Minute * 30)
+	if _, err := h.storage.Users.CreatePasswordReset(user.Id, hashedToken, passwordResetExpirationTime); err != nil {
+		log.Printf("failed CreatePasswordReset: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type PasswordResetMailJob struct {
+		Type              string `json:"type"`
+		FromEmail         string `json:"from_email"`
+		ToEmail           string `json:"to_email"`
+		UserId            int    `json:"user_id"`
+		Subject           string `json:"subject"`
+		EmailTemplatePath string `json:"email_template_path"`
+		Token             string `json:"token"`
+	}
+
+	passwordResetMailJob := PasswordResetMailJob{
+		Type:              mailer.MailJobTypePasswordReset,
+		FromEmail:         os.Getenv("MAILER_USERNAME"),
+		ToEmail:           user.Email,
+		UserId:            user.Id,
+		Subject:           "Reset your password",
+		Token:             plainTextToken,
+		EmailTemplatePath: "./templates/password_reset_mail.html",
+	}
+
+	passwordResetMailJobJson, err := json.Marshal(passwordResetMailJob)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.pushJob("queue:email", passwordResetMailJobJson) {
+		log.Printf("failed to push password reset mail job for user %d\n", user.Id)
+	}
+
+	if err := writeJSON(w, response, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// rotates the invitation token of an unverified account and mails a new verification link,
+// responds with the same message whether or not such an account exists
+func (h *Handler) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+
+	var resendVerificationPayload ResendVerificationRequest
+
+	if err := readJSON(r, &resendVerificationPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userEmail := strings.ToLower(strings.TrimSpace(resendVerificationPayload.Email))
+
+	if userEmail == "" {
+		writeJSONError(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	// limits are applied before looking up the account so they don't reveal whether it exists
+	isAllowed, err := h.allowRequest(fmt.Sprintf("ratelimit:resend-verification:cooldown:%s", userEmail), 1, RESEND_VERIFICATION_COOLDOWN)
+	if err != nil {
+		log.Printf("failed to check rate limit: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if isAllowed {
+		isAllowed, err = h.allowRequest(fmt.Sprintf("ratelimit:resend-verification:%s", userEmail), RESEND_VERIFICATION_WINDOW_LIMIT, RESEND_VERIFICATION_WINDOW)
+		if err != nil {
+			log.Printf("failed to check rate limit: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if !isAllowed {
+		writeJSONError(w, "too many verification requests, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	response := Response{Success: true, Message: "if an unverified account exists with this email, a verification link has been sent"}
+
+	// an already verified email has nothing to resend
+	verifiedUser, err := h.storage.Users.GetVerifiedUserByEmail(userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed query GetVerifiedUserByEmail: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if verifiedUser != nil {
+		if err := writeJSON(w, response, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	user, err := h.storage.Users.GetUnverifiedUserByEmail(userEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := writeJSON(w, response, http.StatusOK); err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		} else {
+			log.Printf("failed query GetUnverifiedUserByEmail: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	plainTextToken := generateToken(32)
+	hashedToken := hashPlainTextToken(plainTextToken)
+
+	if _, err := h.storage.Users.RotateUserInvitation(user.Id, hashedToken, time.Now().Add(USER_INVITATION_TTL)); err != nil {
+		log.Printf("failed RotateUserInvitation: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type VerificationMailJob struct {
+		Type              string `json:"type"`
+		FromEmail         string `json:"from_email"`
+		ToEmail           string `json:"to_email"`
+		UserId            int    `json:"user_id"`
+		Subject           string `json:"subject"`
+		EmailTemplatePath string `json:"email_template_path"`
+		Token             string `json:"token"`
+	}
+
+	verificationMailJob := VerificationMailJob{
+		Type:              mailer.MailJobTypeVerification,
+		FromEmail:         os.Getenv("MAILER_USERNAME"),
+		ToEmail:           user.Email,
+		UserId:            user.Id,
+		Subject:           "Verify your account",
+		Token:             plainTextToken,
+		EmailTemplatePath: "./templates/verification_mail.html",
+	}
+
+	verificationMailJobJson, err := json.Marshal(verificationMailJob)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.pushJob("queue:email", verificationMailJobJson) {
+		log.Printf("failed to push verification mail job for user %d\n", user.Id)
+	}
+
+	if err := writeJSON(w, response, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+
+	plainTextToken := chi.URLParam(r, "token")
+	hashedToken := hashPlainTextToken(plainTextToken)
+
+	var resetPasswordPayload ResetPasswordRequest
+
+	if err := readJSON(r, &resetPasswordPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	newPlainTextPassword := strings.TrimSpace(resetPasswordPayload.Password)
+
+	if newPlainTextPassword == "" {
+		writeJSONError(w, "password is required", http.StatusBadRequest)
+		return
+	}
+
+	if !utils.IsPasswordStrong(newPlainTextPassword) {
+		writeJSONError(w, "password is weak", http.StatusBadRequest)
+		return
+	}
+
+	hashedPasswordByteArr, err := bcrypt.GenerateFromPassword([]byte(newPlainTextPassword), bcrypt.DefaultCost)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.ResetPassword(hashedToken, string(hashedPasswordByteArr))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invalid or expired password reset token", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed ResetPassword: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// sign out everywhere, whoever requested the reset may not be the only one holding a session
+	if err := h.storage.RefreshTokens.RevokeUserRefreshTokens(user.Id); err != nil {
+		log.Printf("failed to revoke refresh tokens after password reset: %v\n", err)
+	}
+
+	if err := h.revokeAllSessions(user.Id); err != nil {
+		log.Printf("failed to revoke sessions after password reset: %v\n", err)
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "password reset successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	cookie, err := r.Cookie("refresh_token")
+	if err != nil {
+		writeJSONError(w, "refresh token not found", http.StatusUnauthorized)
+		return
+	}
+
+	hashedToken := hashPlainTextToken(cookie.Value)
+
+	refreshToken, err := h.storage.RefreshTokens.GetRefreshToken(hashedToken)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invalid refresh token", http.StatusUnauthorized)
+			return
+		} else {
+			log.Printf("failed to get refresh token: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if refreshToken.RotatedAt != nil || refreshToken.RevokedAt != nil {
+		// an already rotated token is being replayed, assume it was stolen and end the whole login
+		if err := h.revokeRefreshTokenFamily(refreshToken.UserId, refreshToken.FamilyId); err != nil {
+			log.Printf("failed to revoke refresh token family: %v\n", err)
+		}
+		clearAuthCookies(w)
+		writeJSONError(w, "refresh token reuse detected", http.StatusUnauthorized)
+		return
+	}
 
-	var sameSiteConfig http.SameSite
+	session, err := h.getSession(refreshToken.FamilyId)
+	if err != nil {
+		log.Printf("failed to get session: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if session == nil || session.UserId != refreshToken.UserId {
+		clearAuthCookies(w)
+		writeJSONError(w, "session expired or revoked", http.StatusUnauthorized)
+		return
+	}
+
+	newPlainTextToken := generateToken(32)
+	newHashedToken := hashPlainTextToken(newPlainTextToken)
+
+	if _, err := h.storage.RefreshTokens.RotateRefreshToken(hashedToken, newHashedToken, time.Now().Add(REFRESH_TOKEN_TTL)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// lost a race with another refresh using the same token, or the token expired
+			if err := h.revokeRefreshTokenFamily(refreshToken.UserId, refreshToken.FamilyId); err != nil {
+				log.Printf("failed to revoke refresh token family: %v\n", err)
+			}
+			clearAuthCookies(w)
+			writeJSONError(w, "invalid refresh token", http.StatusUnauthorized)
+			return
+		} else {
+			log.Printf("failed to rotate refresh token: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	accessTokenStr, err := signAccessToken(refreshToken.UserId, refreshToken.FamilyId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.extendSession(session.UserId, session.Id); err != nil {
+		log.Printf("failed to extend session: %v\n", err)
+	}
+
+	setAuthCookies(w, accessTokenStr, newPlainTextToken)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "refreshed auth token"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// issues a fresh access token and the first refresh token of a new token family for the session
+func (h *Handler) issueAuthTokens(w http.ResponseWriter, userId int, sessionId string) error {
+
+	accessTokenStr, err := signAccessToken(userId, sessionId)
+	if err != nil {
+		return err
+	}
+
+	plainTextRefreshToken := generateToken(32)
+	hashedRefreshToken := hashPlainTextToken(plainTextRefreshToken)
+
+	if _, err := h.storage.RefreshTokens.CreateRefreshToken(hashedRefreshToken, userId, sessionId, time.Now().Add(REFRESH_TOKEN_TTL)); err != nil {
+		return err
+	}
+
+	setAuthCookies(w, accessTokenStr, plainTextRefreshToken)
+
+	return nil
+}
+
+// the session id doubles as the refresh token family id
+func (h *Handler) revokeRefreshTokenFamily(userId int, familyId string) error {
+
+	if err := h.storage.RefreshTokens.RevokeRefreshTokenFamily(familyId); err != nil {
+		return err
+	}
+
+	return h.revokeSession(userId, familyId)
+}
+
+func signAccessToken(userId int, sessionId string) (string, error) {
+
+	claims := jwt.MapClaims{
+		"sub": userId,
+		"sid": sessionId,
+		"exp": time.Now().Add(ACCESS_TOKEN_TTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JWT_SECRET)
+}
+
+func authCookieSameSite() http.SameSite {
 
 	if os.Getenv("GO_ENV") == "production" {
-		sameSiteConfig = http.SameSiteNoneMode
-	} else {
-		sameSiteConfig = http.SameSiteLaxMode
+		return http.SameSiteNoneMode
 	}
 
-	cookie := http.Cookie{
+	return http.SameSiteLaxMode
+}
+
+func setAuthCookies(w http.ResponseWriter, accessTokenStr string, refreshTokenStr string) {
+
+	accessTokenCookie := http.Cookie{
 		Name:     "auth_token",
-		Value:    "",
+		Value:    accessTokenStr,
 		HttpOnly: true,
 		Secure:   os.Getenv("GO_ENV") == "production",
-		SameSite: sameSiteConfig,
+		SameSite: authCookieSameSite(),
 		Path:     "/",
-		MaxAge:   0,
+		MaxAge:   int(ACCESS_TOKEN_TTL.Seconds()),
 	}
 
-	http.SetCookie(w, &cookie)
+	// the refresh token is only ever sent to the auth routes
+	refreshTokenCookie := http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshTokenStr,
+		HttpOnly: true,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		SameSite: authCookieSameSite(),
+		Path:     "/api/auth",
+		MaxAge:   int(REFRESH_TOKEN_TTL.Seconds()),
+	}
 
-	type Response struct {
-		Success bool   `json:"success"`
-		Message string `json:"message"`
+	http.SetCookie(w, &accessTokenCookie)
+	http.SetCookie(w, &refreshTokenCookie)
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+
+	accessTokenCookie := http.Cookie{
+		Name:     "auth_token",
+		Value:    "",
+		HttpOnly: true,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		SameSite: authCookieSameSite(),
+		Path:     "/",
+		MaxAge:   -1,
 	}
 
-	if err := writeJSON(w, Response{Success: true, Message: "logged out successfully"}, http.StatusOK); err != nil {
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	refreshTokenCookie := http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		HttpOnly: true,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		SameSite: authCookieSameSite(),
+		Path:     "/api/auth",
+		MaxAge:   -1,
 	}
+
+	http.SetCookie(w, &accessTokenCookie)
+	http.SetCookie(w, &refreshTokenCookie)
 }
 
 func generateToken(n int) string {
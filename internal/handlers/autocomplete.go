@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	MAX_AUTOCOMPLETE_PREFIX_LENGTH = 50
+	DEFAULT_AUTOCOMPLETE_LIMIT     = 10
+	MAX_AUTOCOMPLETE_LIMIT         = 20
+
+	// suggestions are cached in redis per prefix, so a new community or topic can take this long to show up
+	AUTOCOMPLETE_CACHE_TTL = time.Minute
+)
+
+func autocompleteKey(prefix string, suggestionTypes []storage.AutocompleteType, limit int) string {
+
+	typeStrs := make([]string, len(suggestionTypes))
+	for i, suggestionType := range suggestionTypes {
+		typeStrs[i] = string(suggestionType)
+	}
+
+	return fmt.Sprintf("autocomplete:%s:%d:%s", strings.Join(typeStrs, ","), limit, prefix)
+}
+
+// getAutocompleteSuggestions gets the suggestions from the redis cache, falling back to the database
+// when they aren't cached or redis is unavailable
+func (h *Handler) getAutocompleteSuggestions(prefix string, suggestionTypes []storage.AutocompleteType, limit int) ([]storage.AutocompleteSuggestion, error) {
+
+	ctx := context.Background()
+	key := autocompleteKey(prefix, suggestionTypes, limit)
+
+	cached, err := h.rdb.Get(ctx, key).Result()
+	if err == nil {
+
+		var suggestions []storage.AutocompleteSuggestion
+
+		if err := json.Unmarshal([]byte(cached), &suggestions); err == nil {
+			return suggestions, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		log.Printf("failed to get cached autocomplete suggestions: %v\n", err)
+	}
+
+	suggestions, err := h.storage.Autocomplete.Autocomplete(prefix, suggestionTypes, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if suggestions == nil {
+		suggestions = []storage.AutocompleteSuggestion{}
+	}
+
+	suggestionsJson, err := json.Marshal(suggestions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.rdb.Set(ctx, key, suggestionsJson, AUTOCOMPLETE_CACHE_TTL).Err(); err != nil {
+		log.Printf("failed to cache autocomplete suggestions: %v\n", err)
+	}
+
+	return suggestions, nil
+}
+
+// no auth required, ?q=go&type=community,topic&limit=10
+// the most popular communities, topics and users (all of them without ?type) whose names start with q
+func (h *Handler) AutocompleteHandler(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	prefix := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if prefix == "" {
+		writeJSONError(w, "query param q is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(prefix) > MAX_AUTOCOMPLETE_PREFIX_LENGTH {
+		writeJSONError(w, "q should be at most "+strconv.Itoa(MAX_AUTOCOMPLETE_PREFIX_LENGTH)+" characters", http.StatusBadRequest)
+		return
+	}
+
+	suggestionTypes := storage.AutocompleteTypes
+
+	if r.URL.Query().Get("type") != "" {
+
+		suggestionTypes = nil
+
+		for _, suggestionTypeStr := range strings.Split(r.URL.Query().Get("type"), ",") {
+
+			suggestionType := storage.AutocompleteType(strings.TrimSpace(suggestionTypeStr))
+
+			if !slices.Contains(storage.AutocompleteTypes, suggestionType) {
+				writeJSONError(w, "type should be community, topic or user", http.StatusBadRequest)
+				return
+			}
+
+			if !slices.Contains(suggestionTypes, suggestionType) {
+				suggestionTypes = append(suggestionTypes, suggestionType)
+			}
+		}
+
+		// the same types in another order share a cache entry
+		slices.SortFunc(suggestionTypes, func(a, b storage.AutocompleteType) int {
+			return slices.Index(storage.AutocompleteTypes, a) - slices.Index(storage.AutocompleteTypes, b)
+		})
+	}
+
+	limit := DEFAULT_AUTOCOMPLETE_LIMIT
+
+	if r.URL.Query().Get("limit") != "" {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > MAX_AUTOCOMPLETE_LIMIT {
+			writeJSONError(w, "limit should be between 1 and "+strconv.Itoa(MAX_AUTOCOMPLETE_LIMIT), http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestions, err := h.getAutocompleteSuggestions(prefix, suggestionTypes, limit)
+	if err != nil {
+		log.Printf("failed to get autocomplete suggestions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool                             `json:"success"`
+		Suggestions []storage.AutocompleteSuggestion `json:"suggestions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Suggestions: suggestions}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
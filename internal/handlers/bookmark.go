@@ -0,0 +1,515 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	MAX_BOOKMARK_COLLECTION_NAME_LENGTH = 50
+)
+
+type CreateBookmarkCollectionRequest struct {
+	CollectionName string `json:"collection_name"`
+}
+
+type UpdateBookmarkCollectionRequest struct {
+	CollectionName string `json:"collection_name"`
+}
+
+// collection_id null moves the bookmark out of its collection
+type UpdatePostBookmarkCollectionRequest struct {
+	CollectionId *int `json:"collection_id"`
+}
+
+// ?page=1&limit=10&collectionId=1
+func (h *Handler) GetBookmarkedPostsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var page int
+	var limit int
+	var collectionId *int
+
+	page, limit, err = parsePagination(r, 10, MAX_POSTS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("collectionId") != "" {
+
+		id, err := strconv.Atoi(r.URL.Query().Get("collectionId"))
+		if err != nil {
+			writeJSONError(w, "invalid request param collectionId", http.StatusBadRequest)
+			return
+		}
+
+		collection, err := h.storage.BookmarkCollections.GetBookmarkCollectionById(id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "collection not found", http.StatusNotFound)
+				return
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if collection.CollectionOwnerId != user.Id {
+			writeJSONError(w, "collection not found", http.StatusNotFound)
+			return
+		}
+
+		collectionId = &collection.Id
+	}
+
+	skip := page*limit - limit
+
+	posts, err := h.storage.Posts.GetUserBookmarkedPosts(user.Id, collectionId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get bookmarked posts: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.setPostsViewerState(user.Id, posts); err != nil {
+		log.Printf("failed to get posts viewer state: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalPostsCount, err := h.storage.Posts.GetUserBookmarkedPostsCount(user.Id, collectionId)
+	if err != nil {
+		log.Printf("failed to get bookmarked posts count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalPostsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                       `json:"success"`
+		Posts     []storage.PostWithMetaData `json:"posts"`
+		NoOfPages int                        `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Posts: posts, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ?page=1&limit=10
+func (h *Handler) GetLikedPostsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var page int
+	var limit int
+
+	page, limit, err = parsePagination(r, 10, MAX_POSTS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	posts, err := h.storage.Posts.GetUserLikedPosts(user.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get liked posts: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.setPostsViewerState(user.Id, posts); err != nil {
+		log.Printf("failed to get posts viewer state: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalPostsCount, err := h.storage.Posts.GetUserLikedPostsCount(user.Id)
+	if err != nil {
+		log.Printf("failed to get liked posts count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalPostsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                       `json:"success"`
+		Posts     []storage.PostWithMetaData `json:"posts"`
+		NoOfPages int                        `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Posts: posts, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) UpdatePostBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	var updatePostBookmarkCollectionPayload UpdatePostBookmarkCollectionRequest
+
+	if err := readJSON(r, &updatePostBookmarkCollectionPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if updatePostBookmarkCollectionPayload.CollectionId != nil {
+
+		collection, err := h.storage.BookmarkCollections.GetBookmarkCollectionById(*updatePostBookmarkCollectionPayload.CollectionId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "collection not found", http.StatusNotFound)
+				return
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if collection.CollectionOwnerId != user.Id {
+			writeJSONError(w, "collection not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	postBookmark, err := h.storage.BookmarkCollections.UpdatePostBookmarkCollection(user.Id, postId, updatePostBookmarkCollectionPayload.CollectionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "bookmark not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to update bookmark collection: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success      bool                 `json:"success"`
+		Message      string               `json:"message"`
+		PostBookmark storage.PostBookmark `json:"post_bookmark"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "updated bookmark collection", PostBookmark: *postBookmark}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetBookmarkCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	collections, err := h.storage.BookmarkCollections.GetUserBookmarkCollections(user.Id)
+	if err != nil {
+		log.Printf("failed to get bookmark collections: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool                                     `json:"success"`
+		Collections []storage.BookmarkCollectionWithMetaData `json:"collections"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Collections: collections}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) CreateBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var createBookmarkCollectionPayload CreateBookmarkCollectionRequest
+
+	if err := readJSON(r, &createBookmarkCollectionPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	collectionName := strings.TrimSpace(createBookmarkCollectionPayload.CollectionName)
+
+	if collectionName == "" {
+		writeJSONError(w, "collection name is required", http.StatusBadRequest)
+		return
+	}
+
+	if utf8.RuneCountInString(collectionName) > MAX_BOOKMARK_COLLECTION_NAME_LENGTH {
+		writeJSONError(w, fmt.Sprintf("collection name cannot be longer than %d characters", MAX_BOOKMARK_COLLECTION_NAME_LENGTH), http.StatusBadRequest)
+		return
+	}
+
+	existingCollection, err := h.storage.BookmarkCollections.GetBookmarkCollectionByName(collectionName, user.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingCollection != nil {
+		writeJSONError(w, "collection already exists", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.storage.BookmarkCollections.CreateBookmarkCollection(collectionName, user.Id)
+	if err != nil {
+		log.Printf("failed to create bookmark collection: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success    bool                       `json:"success"`
+		Message    string                     `json:"message"`
+		Collection storage.BookmarkCollection `json:"collection"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "created collection", Collection: *collection}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) UpdateBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	collectionId, err := strconv.Atoi(chi.URLParam(r, "collectionId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param collectionId", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.storage.BookmarkCollections.GetBookmarkCollectionById(collectionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "collection not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if collection.CollectionOwnerId != user.Id {
+		writeJSONError(w, "collection not found", http.StatusNotFound)
+		return
+	}
+
+	var updateBookmarkCollectionPayload UpdateBookmarkCollectionRequest
+
+	if err := readJSON(r, &updateBookmarkCollectionPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	newCollectionName := strings.TrimSpace(updateBookmarkCollectionPayload.CollectionName)
+
+	if newCollectionName == "" {
+		writeJSONError(w, "collection name is required", http.StatusBadRequest)
+		return
+	}
+
+	if utf8.RuneCountInString(newCollectionName) > MAX_BOOKMARK_COLLECTION_NAME_LENGTH {
+		writeJSONError(w, fmt.Sprintf("collection name cannot be longer than %d characters", MAX_BOOKMARK_COLLECTION_NAME_LENGTH), http.StatusBadRequest)
+		return
+	}
+
+	collectionWithNewName, err := h.storage.BookmarkCollections.GetBookmarkCollectionByName(newCollectionName, user.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if collectionWithNewName != nil && collectionWithNewName.Id != collection.Id {
+		writeJSONError(w, "collection with new name already exists", http.StatusBadRequest)
+		return
+	}
+
+	updatedCollection, err := h.storage.BookmarkCollections.UpdateBookmarkCollectionName(collection.Id, newCollectionName)
+	if err != nil {
+		log.Printf("failed to update bookmark collection: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success    bool                       `json:"success"`
+		Message    string                     `json:"message"`
+		Collection storage.BookmarkCollection `json:"collection"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "updated collection", Collection: *updatedCollection}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// deleting a collection keeps its bookmarks, they become unfiled
+func (h *Handler) DeleteBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	collectionId, err := strconv.Atoi(chi.URLParam(r, "collectionId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param collectionId", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.storage.BookmarkCollections.GetBookmarkCollectionById(collectionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "collection not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if collection.CollectionOwnerId != user.Id {
+		writeJSONError(w, "collection not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.storage.BookmarkCollections.DeleteBookmarkCollectionById(collection.Id); err != nil {
+		log.Printf("failed to delete bookmark collection: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "deleted collection"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
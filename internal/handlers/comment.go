@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"math"
 	"net/http"
 	"strconv"
@@ -17,6 +18,18 @@ type CreatePostCommentRequest struct {
 	ParentCommentId *int   `json:"parent_comment_id"`
 }
 
+const (
+	DEFAULT_COMMENT_TREE_DEPTH    = 3
+	MAX_COMMENT_TREE_DEPTH        = 10
+	DEFAULT_COMMENT_TREE_CHILDREN = 10
+	MAX_COMMENT_TREE_CHILDREN     = 50
+	MAX_COMMENT_TREE_NODES        = 500 // depth and limit alone would allow 50^10 comments
+)
+
+type UpdatePostCommentRequest struct {
+	CommentContent string `json:"comment_content"`
+}
+
 // /:postId
 func (h *Handler) CreatePostCommentHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -54,6 +67,30 @@ func (h *Handler) CreatePostCommentHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	canParticipate, err := h.canParticipateInPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canParticipate {
+		writeJSONError(w, "only community members can participate", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, true)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
 	var createPostCommentPayload CreatePostCommentRequest
 
 	if err := readJSON(r, &createPostCommentPayload); err != nil {
@@ -84,6 +121,11 @@ func (h *Handler) CreatePostCommentHandler(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
+		if parentComment.CommentDeletedAt != nil {
+			writeJSONError(w, "cannot reply to a deleted comment", http.StatusBadRequest)
+			return
+		}
+
 		postChildComment, err := h.storage.PostComments.CreateChildPostComment(commentContent, user.Id, post.Id, parentComment.Id)
 		if err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -159,12 +201,48 @@ func (h *Handler) DeletePostCommentHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	if user.Id != comment.CommentOwnerId {
-		writeJSONError(w, "user not authorized to delete comment", http.StatusUnauthorized)
+	if comment.CommentDeletedAt != nil {
+		writeJSONError(w, "comment is already deleted", http.StatusBadRequest)
 		return
 	}
 
-	if err = h.storage.PostComments.DeletePostCommentById(comment.Id); err != nil {
+	// comments can be deleted by their owner or a moderator (owner included) of the post's community,
+	// moderators removing someone else's comment is recorded in the audit log along with ?reason=
+	var audit *storage.AuditLogEntry
+
+	if user.Id != comment.CommentOwnerId {
+
+		post, err := h.storage.Posts.GetPostById(comment.PostId)
+		if err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		isModerator, err := h.hasCommunityRole(user.Id, post.PostCommunityId, storage.CommunityRoleModerator)
+		if err != nil {
+			log.Printf("failed to get community role: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if !isModerator {
+			writeJSONError(w, "user not authorized to delete comment", http.StatusUnauthorized)
+			return
+		}
+
+		audit = &storage.AuditLogEntry{
+			ActorId:     user.Id,
+			CommunityId: &post.PostCommunityId,
+			Action:      storage.AuditActionCommentDelete,
+			TargetType:  storage.AuditTargetComment,
+			TargetId:    comment.Id,
+			Before:      comment,
+			Reason:      strings.TrimSpace(r.URL.Query().Get("reason")),
+		}
+	}
+
+	if err = h.storage.PostComments.DeletePostCommentById(comment.Id, audit); err != nil {
+		log.Printf("failed to delete comment: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -179,6 +257,183 @@ func (h *Handler) DeletePostCommentHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// only the author can edit a comment, the content it replaces is kept in the comment's revisions
+func (h *Handler) UpdatePostCommentHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	commentId, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param commentId", http.StatusBadRequest)
+		return
+	}
+
+	var updatePostCommentPayload UpdatePostCommentRequest
+
+	if err := readJSON(r, &updatePostCommentPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	commentContent := strings.TrimSpace(updatePostCommentPayload.CommentContent)
+	if commentContent == "" {
+		writeJSONError(w, "comment content is required", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.storage.PostComments.GetPostCommentById(commentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post comment not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if comment.CommentOwnerId != user.Id {
+		writeJSONError(w, "only the author can edit comment", http.StatusForbidden)
+		return
+	}
+
+	if comment.CommentDeletedAt != nil {
+		writeJSONError(w, "cannot edit a deleted comment", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(comment.PostId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, true)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	if commentContent == comment.CommentContent {
+		writeJSONError(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	updatedComment, err := h.storage.PostComments.UpdatePostCommentById(comment.Id, commentContent, user.Id)
+	if err != nil {
+		log.Printf("failed to update comment: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool                `json:"success"`
+		Message     string              `json:"message"`
+		PostComment storage.PostComment `json:"post_comment"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "updated comment", PostComment: *updatedComment}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// no auth required, lists the previous versions of a comment
+func (h *Handler) GetCommentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	commentId, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param commentId", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.storage.PostComments.GetPostCommentById(commentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post comment not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	post, err := h.storage.Posts.GetPostById(comment.PostId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// private communities are only readable by their members
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	isVisible, err := h.canViewPost(userId, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_REVISIONS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	revisions, err := h.storage.PostComments.GetCommentRevisions(comment.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get comment revisions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalRevisionsCount, err := h.storage.PostComments.GetCommentRevisionsCount(comment.Id)
+	if err != nil {
+		log.Printf("failed to get comment revisions count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalRevisionsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                      `json:"success"`
+		Revisions []storage.CommentRevision `json:"revisions"`
+		NoOfPages int                       `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Revisions: revisions, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) ToggleCommentLikeHandler(w http.ResponseWriter, r *http.Request) {
 
 	userId, ok := r.Context().Value(AuthUserId).(int)
@@ -215,11 +470,53 @@ func (h *Handler) ToggleCommentLikeHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	isCommentLiked, _ := h.storage.PostComments.CheckCommentLike(user.Id, comment.Id)
+	if comment.CommentDeletedAt != nil {
+		writeJSONError(w, "cannot like a deleted comment", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(comment.PostId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	canParticipate, err := h.canParticipateInPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canParticipate {
+		writeJSONError(w, "only community members can participate", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, false)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	// a like is an upvote, liking a comment the user already upvoted removes the upvote
+	commentVote, err := h.storage.PostComments.GetCommentVote(user.Id, comment.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	isCommentLiked := commentVote != nil && commentVote.VoteValue == storage.VoteUp
 
 	if isCommentLiked {
 
-		if err := h.storage.PostComments.RemoveCommentLike(user.Id, comment.Id); err != nil {
+		if err := h.storage.PostComments.RemoveCommentVote(user.Id, comment.Id); err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -234,7 +531,7 @@ func (h *Handler) ToggleCommentLikeHandler(w http.ResponseWriter, r *http.Reques
 		}
 	} else {
 
-		postCommentLike, err := h.storage.PostComments.CreateCommentLike(user.Id, comment.Id)
+		postCommentVote, err := h.storage.PostComments.VoteComment(user.Id, comment.Id, storage.VoteUp)
 		if err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 
@@ -244,10 +541,10 @@ func (h *Handler) ToggleCommentLikeHandler(w http.ResponseWriter, r *http.Reques
 		type Response struct {
 			Success         bool                    `json:"success"`
 			Message         string                  `json:"message"`
-			PostCommentLike storage.PostCommentLike `json:"post_comment_like"`
+			PostCommentVote storage.PostCommentVote `json:"post_comment_vote"`
 		}
 
-		if err := writeJSON(w, Response{Success: true, Message: "comment liked", PostCommentLike: *postCommentLike}, http.StatusCreated); err != nil {
+		if err := writeJSON(w, Response{Success: true, Message: "comment liked", PostCommentVote: *postCommentVote}, http.StatusCreated); err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		}
 
@@ -255,7 +552,145 @@ func (h *Handler) ToggleCommentLikeHandler(w http.ResponseWriter, r *http.Reques
 
 }
 
-// no auth required to view post comments
+// sets the user's vote on a comment, a vote value of 0 removes it
+func (h *Handler) VoteCommentHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	commentId, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param commentId", http.StatusBadRequest)
+		return
+	}
+
+	var votePayload VoteRequest
+
+	if err := readJSON(r, &votePayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if votePayload.VoteValue == nil || (*votePayload.VoteValue != storage.VoteUp && *votePayload.VoteValue != storage.VoteDown && *votePayload.VoteValue != 0) {
+		writeJSONError(w, "vote value should be 1, -1 or 0", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.storage.PostComments.GetPostCommentById(commentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post comment not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if comment.CommentDeletedAt != nil {
+		writeJSONError(w, "cannot vote on a deleted comment", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(comment.PostId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	canParticipate, err := h.canParticipateInPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canParticipate {
+		writeJSONError(w, "only community members can participate", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, false)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	if *votePayload.VoteValue == 0 {
+
+		if err := h.storage.PostComments.RemoveCommentVote(user.Id, comment.Id); err != nil {
+			log.Printf("failed to remove comment vote: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "removed vote"}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	postCommentVote, err := h.storage.PostComments.VoteComment(user.Id, comment.Id, *votePayload.VoteValue)
+	if err != nil {
+		log.Printf("failed to vote comment: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success         bool                    `json:"success"`
+		Message         string                  `json:"message"`
+		PostCommentVote storage.PostCommentVote `json:"post_comment_vote"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "voted comment", PostCommentVote: *postCommentVote}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// parseCommentsSortBy reads ?sortBy for comment listings, new (the default), old, top, best or controversial
+func parseCommentsSortBy(r *http.Request) (storage.SortByStr, bool) {
+
+	if r.URL.Query().Get("sortBy") == "" {
+		return storage.SortByNewest, true
+	}
+
+	sortBy := storage.SortByStr(r.URL.Query().Get("sortBy"))
+
+	switch sortBy {
+	case storage.SortByNewest, storage.SortByOldest, storage.SortByTop, storage.SortByBest, storage.SortByControversial:
+		return sortBy, true
+	default:
+		return "", false
+	}
+}
+
+// no auth required to view post comments, ?sortBy=new|old|top|best|controversial
 func (h *Handler) GetPostCommentsHandler(w http.ResponseWriter, r *http.Request) {
 
 	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
@@ -275,6 +710,21 @@ func (h *Handler) GetPostCommentsHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// private communities are only readable by their members
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	isVisible, err := h.canViewPost(userId, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
 	var page int
 	var limit int
 
@@ -298,11 +748,23 @@ func (h *Handler) GetPostCommentsHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	skip := page*limit - limit
+	sortBy, ok := parseCommentsSortBy(r)
+	if !ok {
+		writeJSONError(w, "invalid query param sortBy", http.StatusBadRequest)
+		return
+	}
 
-	postComments, err := h.storage.PostComments.GetPostComments(post.Id, skip, limit) // get post comments by recency (top comment will be most recent)
+	skip := page*limit - limit
 
+	postComments, err := h.storage.PostComments.GetPostComments(post.Id, skip, limit, sortBy)
 	if err != nil {
+		log.Printf("failed to get post comments: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.Reactions.SetCommentsReactions(userId, commentsReactionTargets(postComments)); err != nil {
+		log.Printf("failed to get comments reactions: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -327,7 +789,7 @@ func (h *Handler) GetPostCommentsHandler(w http.ResponseWriter, r *http.Request)
 
 }
 
-// no auth required
+// no auth required, ?sortBy=new|old|top|best|controversial
 func (h *Handler) GetCommentRepliesHandler(w http.ResponseWriter, r *http.Request) {
 
 	commentId, err := strconv.Atoi(chi.URLParam(r, "commentId"))
@@ -347,6 +809,27 @@ func (h *Handler) GetCommentRepliesHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	post, err := h.storage.Posts.GetPostById(comment.PostId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// private communities are only readable by their members
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	isVisible, err := h.canViewPost(userId, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
 	var page int
 	var limit int
 
@@ -370,10 +853,23 @@ func (h *Handler) GetCommentRepliesHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	sortBy, ok := parseCommentsSortBy(r)
+	if !ok {
+		writeJSONError(w, "invalid query param sortBy", http.StatusBadRequest)
+		return
+	}
+
 	skip := page*limit - limit
 
-	commentReplies, err := h.storage.PostComments.GetCommentReplies(comment.Id, skip, limit)
+	commentReplies, err := h.storage.PostComments.GetCommentReplies(comment.Id, skip, limit, sortBy)
 	if err != nil {
+		log.Printf("failed to get comment replies: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.Reactions.SetCommentsReactions(userId, commentsReactionTargets(commentReplies)); err != nil {
+		log.Printf("failed to get comments reactions: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -396,3 +892,92 @@ func (h *Handler) GetCommentRepliesHandler(w http.ResponseWriter, r *http.Reques
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
+
+// no auth required, the post's comments as a tree. ?depth limits how many levels are loaded and ?limit how many
+// replies are loaded under each comment. comments with more replies carry a replies_cursor, passing it as ?cursor
+// loads the rest of those replies (and the replies under them), next_cursor continues the loaded level itself
+func (h *Handler) GetPostCommentTreeHandler(w http.ResponseWriter, r *http.Request) {
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(postId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// private communities are only readable by their members
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	isVisible, err := h.canViewPost(userId, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
+	depth := DEFAULT_COMMENT_TREE_DEPTH
+	limit := DEFAULT_COMMENT_TREE_CHILDREN
+	var cursor *storage.CommentCursor
+
+	if r.URL.Query().Get("depth") != "" {
+		depth, err = strconv.Atoi(r.URL.Query().Get("depth"))
+		if err != nil || depth < 1 || depth > MAX_COMMENT_TREE_DEPTH {
+			writeJSONError(w, "depth should be between 1 and "+strconv.Itoa(MAX_COMMENT_TREE_DEPTH), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.URL.Query().Get("limit") != "" {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > MAX_COMMENT_TREE_CHILDREN {
+			writeJSONError(w, "limit should be between 1 and "+strconv.Itoa(MAX_COMMENT_TREE_CHILDREN), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.URL.Query().Get("cursor") != "" {
+		cursor, err = storage.DecodeCommentCursor(r.URL.Query().Get("cursor"))
+		if err != nil {
+			writeJSONError(w, "invalid query param cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comments, nextCursor, err := h.storage.PostComments.GetCommentTree(post.Id, cursor, depth, limit, MAX_COMMENT_TREE_NODES)
+	if err != nil {
+		log.Printf("failed to get comment tree: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.Reactions.SetCommentsReactions(userId, commentTreeReactionTargets(comments)); err != nil {
+		log.Printf("failed to get comments reactions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success    bool                      `json:"success"`
+		Comments   []storage.CommentTreeNode `json:"comments"`
+		NextCursor *string                   `json:"next_cursor"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Comments: comments, NextCursor: nextCursor}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
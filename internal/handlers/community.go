@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dhruv15803/go-community-platform/internal/storage"
 	"github.com/go-chi/chi/v5"
@@ -18,14 +19,45 @@ type CreateCommunityRequest struct {
 	CommunityName        string  `json:"community_name"`
 	CommunityDescription string  `json:"community_description"`
 	CommunityImage       string  `json:"community_image"`
+	CommunityVisibility  string  `json:"community_visibility"` // defaults to public
 	CommunityTopics      []Topic `json:"community_topics"`
 }
 
+// omitted fields are left unchanged
+type UpdateCommunityRequest struct {
+	CommunityName        *string  `json:"community_name"`
+	CommunityDescription *string  `json:"community_description"`
+	CommunityImage       *string  `json:"community_image"`
+	CommunityVisibility  *string  `json:"community_visibility"`
+	CommunityTopics      *[]Topic `json:"community_topics"`
+}
+
+// the owner confirms the deletion by typing the community's name
+type DeleteCommunityRequest struct {
+	CommunityName string `json:"community_name"`
+}
+
+type TransferCommunityOwnershipRequest struct {
+	UserId int `json:"user_id"`
+}
+
 const (
 	MIN_COMMUNITY_TOPICS = 1
 	MAX_COMMUNITY_TOPICS = 3
+
+	COMMUNITY_OWNERSHIP_TRANSFER_TTL = time.Hour * 24 * 7
 )
 
+func parseCommunityVisibility(visibility string) (storage.CommunityVisibility, bool) {
+
+	switch storage.CommunityVisibility(visibility) {
+	case storage.CommunityVisibilityPublic, storage.CommunityVisibilityRestricted, storage.CommunityVisibilityPrivate:
+		return storage.CommunityVisibility(visibility), true
+	default:
+		return "", false
+	}
+}
+
 func (h *Handler) CreateCommunityHandler(w http.ResponseWriter, r *http.Request) {
 
 	userId, ok := r.Context().Value(AuthUserId).(int)
@@ -62,6 +94,16 @@ func (h *Handler) CreateCommunityHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	communityVisibility := storage.CommunityVisibilityPublic
+	if createCommunityPayload.CommunityVisibility != "" {
+		visibility, ok := parseCommunityVisibility(createCommunityPayload.CommunityVisibility)
+		if !ok {
+			writeJSONError(w, "community visibility should be public, restricted or private", http.StatusBadRequest)
+			return
+		}
+		communityVisibility = visibility
+	}
+
 	// check if a community already exists with communityName
 	existingCommunity, err := h.storage.Communities.GetCommunityByName(communityName)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
@@ -110,7 +152,7 @@ func (h *Handler) CreateCommunityHandler(w http.ResponseWriter, r *http.Request)
 
 	// create new community with community topics
 
-	communityWithTopics, err := h.storage.Communities.CreateCommunityWithTopics(communityName, communityDescription, communityImageUrl, user.Id, uniqueTopicIds)
+	communityWithTopics, err := h.storage.Communities.CreateCommunityWithTopics(communityName, communityDescription, communityImageUrl, user.Id, communityVisibility, uniqueTopicIds)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -177,6 +219,22 @@ func (h *Handler) ToggleJoinCommunityHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// banned users can't join or ask to join, leaving is always allowed
+	if !isPartOfCommunity {
+
+		ban, err := h.getBlockingCommunityBan(user.Id, community.Id, false)
+		if err != nil {
+			log.Printf("failed to check community ban: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if ban != nil {
+			writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+			return
+		}
+	}
+
 	if isPartOfCommunity {
 
 		// remove user from community
@@ -196,6 +254,51 @@ func (h *Handler) ToggleJoinCommunityHandler(w http.ResponseWriter, r *http.Requ
 
 		}
 
+	} else if community.CommunityVisibility != storage.CommunityVisibilityPublic {
+
+		// restricted and private communities need a moderator to approve the request,
+		// toggling again while it is pending cancels it
+		joinRequest, err := h.storage.Communities.GetJoinRequest(user.Id, community.Id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if joinRequest != nil {
+
+			if err := h.storage.Communities.DeleteJoinRequest(user.Id, community.Id); err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			type Response struct {
+				Success bool   `json:"success"`
+				Message string `json:"message"`
+			}
+
+			if err := writeJSON(w, Response{Success: true, Message: "cancelled join request"}, http.StatusOK); err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		joinRequest, err = h.storage.Communities.CreateJoinRequest(user.Id, community.Id)
+		if err != nil {
+			log.Printf("failed to create join request: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success     bool                         `json:"success"`
+			Message     string                       `json:"message"`
+			JoinRequest storage.CommunityJoinRequest `json:"join_request"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "join request sent, waiting for a moderator to approve it", JoinRequest: *joinRequest}, http.StatusAccepted); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+
 	} else {
 
 		userCommunity, err := h.storage.Communities.JoinCommunity(user.Id, community.Id)
@@ -429,3 +532,394 @@ func (h *Handler) GetRecommendedCommunitiesHandler(w http.ResponseWriter, r *htt
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
+
+// owner only
+func (h *Handler) UpdateCommunityHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	community, err := h.storage.Communities.GetCommunityById(communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "community not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var updateCommunityPayload UpdateCommunityRequest
+
+	if err := readJSON(r, &updateCommunityPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	communityName := community.CommunityName
+	communityDescription := community.CommunityDescription
+	communityImage := community.CommunityImage
+	communityVisibility := community.CommunityVisibility
+	var communityTopicIds []int
+
+	if updateCommunityPayload.CommunityName != nil {
+
+		communityName = strings.TrimSpace(*updateCommunityPayload.CommunityName)
+
+		if communityName == "" {
+			writeJSONError(w, "community name is required", http.StatusBadRequest)
+			return
+		}
+
+		existingCommunity, err := h.storage.Communities.GetCommunityByName(communityName)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if existingCommunity != nil && existingCommunity.Id != community.Id {
+			writeJSONError(w, "community with new name already exists", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// an empty description or image clears it
+	if updateCommunityPayload.CommunityDescription != nil {
+		communityDescription = nil
+		if description := strings.TrimSpace(*updateCommunityPayload.CommunityDescription); description != "" {
+			communityDescription = &description
+		}
+	}
+
+	if updateCommunityPayload.CommunityImage != nil {
+
+		image := strings.TrimSpace(*updateCommunityPayload.CommunityImage)
+
+		if image == "" {
+			communityImage = nil
+		} else if community.CommunityImage == nil || image != *community.CommunityImage {
+			// like user images, only images uploaded by this user through /api/file/upload can be linked
+			if !strings.HasPrefix(image, userUploadsUrlPrefix(userId)) {
+				writeJSONError(w, "community image must be uploaded through /api/file/upload", http.StatusBadRequest)
+				return
+			}
+			communityImage = &image
+		}
+	}
+
+	if updateCommunityPayload.CommunityVisibility != nil {
+		visibility, ok := parseCommunityVisibility(*updateCommunityPayload.CommunityVisibility)
+		if !ok {
+			writeJSONError(w, "community visibility should be public, restricted or private", http.StatusBadRequest)
+			return
+		}
+		communityVisibility = visibility
+	}
+
+	if updateCommunityPayload.CommunityTopics != nil {
+
+		communityTopicIds = []int{}
+
+		for _, topic := range *updateCommunityPayload.CommunityTopics {
+
+			if isArrayContainsElement(communityTopicIds, topic.Id) {
+				continue
+			}
+
+			// check if valid topic id
+			topic, err := h.storage.Topics.GetTopicById(topic.Id)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					writeJSONError(w, "topic not found", http.StatusBadRequest)
+					return
+				} else {
+					writeJSONError(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			communityTopicIds = append(communityTopicIds, topic.Id)
+		}
+
+		if len(communityTopicIds) < MIN_COMMUNITY_TOPICS {
+			writeJSONError(w, "community topics is required", http.StatusBadRequest)
+			return
+		}
+
+		if len(communityTopicIds) > MAX_COMMUNITY_TOPICS {
+			writeJSONError(w, fmt.Sprintf("community cannot have more than %d topics", MAX_COMMUNITY_TOPICS), http.StatusBadRequest)
+			return
+		}
+	}
+
+	updatedCommunity, err := h.storage.Communities.UpdateCommunityById(community.Id, communityName, communityDescription, communityImage, communityVisibility, communityTopicIds)
+	if err != nil {
+		log.Printf("failed to update community: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success   bool                        `json:"success"`
+		Message   string                      `json:"message"`
+		Community storage.CommunityWithTopics `json:"community"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "updated community successfully", Community: *updatedCommunity}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// owner only, the request body has to repeat the community's name to confirm the deletion
+func (h *Handler) DeleteCommunityHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	community, err := h.storage.Communities.GetCommunityById(communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "community not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var deleteCommunityPayload DeleteCommunityRequest
+
+	if err := readJSON(r, &deleteCommunityPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(deleteCommunityPayload.CommunityName) != community.CommunityName {
+		writeJSONError(w, "community name does not match, type the community name to confirm deletion", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.Communities.DeleteCommunityById(community.Id); err != nil {
+		log.Printf("failed to delete community: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "deleted community successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// owner only, offers the community to one of its members. Ownership only changes once they accept
+func (h *Handler) TransferCommunityOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	community, err := h.storage.Communities.GetCommunityById(communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "community not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var transferCommunityOwnershipPayload TransferCommunityOwnershipRequest
+
+	if err := readJSON(r, &transferCommunityOwnershipPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if transferCommunityOwnershipPayload.UserId == userId {
+		writeJSONError(w, "user already owns community", http.StatusBadRequest)
+		return
+	}
+
+	isPartOfCommunity, err := h.storage.Communities.CheckCommunityForUser(transferCommunityOwnershipPayload.UserId, community.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isPartOfCommunity {
+		writeJSONError(w, "user is not a member of community", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.storage.Communities.CreateOwnershipTransfer(community.Id, userId, transferCommunityOwnershipPayload.UserId, time.Now().Add(COMMUNITY_OWNERSHIP_TRANSFER_TTL))
+	if err != nil {
+		log.Printf("failed to create ownership transfer: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success  bool                               `json:"success"`
+		Message  string                             `json:"message"`
+		Transfer storage.CommunityOwnershipTransfer `json:"transfer"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "ownership transfer offered, waiting for the user to accept", Transfer: *transfer}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// owner only
+func (h *Handler) CancelCommunityOwnershipTransferHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.Communities.DeleteOwnershipTransfer(communityId); err != nil {
+		log.Printf("failed to delete ownership transfer: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "cancelled ownership transfer"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) AcceptCommunityOwnershipTransferHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	community, err := h.storage.Communities.AcceptOwnershipTransfer(communityId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "ownership transfer not found or expired", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to accept ownership transfer: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success   bool              `json:"success"`
+		Message   string            `json:"message"`
+		Community storage.Community `json:"community"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "accepted ownership transfer", Community: *community}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) DeclineCommunityOwnershipTransferHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.storage.Communities.GetOwnershipTransfer(communityId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if transfer == nil || transfer.ToUserId != userId {
+		writeJSONError(w, "ownership transfer not found or expired", http.StatusNotFound)
+		return
+	}
+
+	if err := h.storage.Communities.DeleteOwnershipTransfer(transfer.CommunityId); err != nil {
+		log.Printf("failed to delete ownership transfer: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "declined ownership transfer"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// pending ownership transfers offered to the authenticated user
+func (h *Handler) GetOwnershipTransfersHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	transfers, err := h.storage.Communities.GetUserOwnershipTransfers(userId)
+	if err != nil {
+		log.Printf("failed to get ownership transfers: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success   bool                                 `json:"success"`
+		Transfers []storage.CommunityOwnershipTransfer `json:"transfers"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Transfers: transfers}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	MAX_COMMUNITY_BAN_REASON_LENGTH = 500
+	MAX_COMMUNITY_BAN_EXPIRY_HOURS  = 24 * 365
+	MAX_COMMUNITY_BANS_LIMIT        = 50
+)
+
+type CommunityBanRequest struct {
+	BanType        string `json:"ban_type"` // ban or mute, defaults to ban
+	BanReason      string `json:"ban_reason"`
+	ExpiresInHours *int   `json:"expires_in_hours"` // permanent when not set
+}
+
+// getBlockingCommunityBan gets the user's active ban in the community, mutes are only
+// returned when includeMutes is set. returns nil when nothing blocks the user
+func (h *Handler) getBlockingCommunityBan(userId int, communityId int, includeMutes bool) (*storage.CommunityBan, error) {
+
+	ban, err := h.storage.CommunityBans.GetActiveCommunityBan(userId, communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if ban.BanType == storage.CommunityBanTypeMute && !includeMutes {
+		return nil, nil
+	}
+
+	return ban, nil
+}
+
+// reports whether the user can ban bannedUserId from the community along with bannedUserId's role.
+// users can only ban users ranked below them, site admins can ban anyone but the owner
+func (h *Handler) canBanCommunityUser(userId int, bannedUserId int, communityId int, isAdmin bool) (bool, storage.CommunityRole, error) {
+
+	bannedUserRole, err := h.storage.Communities.GetCommunityRole(bannedUserId, communityId)
+	if err != nil {
+		return false, "", err
+	}
+
+	if isAdmin {
+		return bannedUserRole != storage.CommunityRoleOwner, bannedUserRole, nil
+	}
+
+	role, err := h.storage.Communities.GetCommunityRole(userId, communityId)
+	if err != nil {
+		return false, "", err
+	}
+
+	return communityRoleRanks[bannedUserRole] < communityRoleRanks[role], bannedUserRole, nil
+}
+
+func communityBanMessage(ban *storage.CommunityBan) string {
+
+	message := "user is banned from community"
+	if ban.BanType == storage.CommunityBanTypeMute {
+		message = "user is muted in community"
+	}
+
+	if ban.Expiration != nil {
+		message += " until " + *ban.Expiration
+	}
+
+	return message + ", reason: " + ban.BanReason
+}
+
+// moderator only, lists active bans and mutes of the community
+func (h *Handler) GetCommunityBansHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_COMMUNITY_BANS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	bans, err := h.storage.CommunityBans.GetCommunityBans(communityId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get community bans: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBansCount, err := h.storage.CommunityBans.GetCommunityBansCount(communityId)
+	if err != nil {
+		log.Printf("failed to get community bans count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalBansCount) / float64(limit)))
+
+	for i := range bans {
+		bans[i].User.Email = ""
+	}
+
+	type Response struct {
+		Success   bool                           `json:"success"`
+		Bans      []storage.CommunityBanWithUser `json:"bans"`
+		NoOfPages int                            `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Bans: bans, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// moderator only, bans or mutes a user. moderators can only ban users ranked below them,
+// so only the owner can ban a moderator
+func (h *Handler) BanCommunityUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	var banPayload CommunityBanRequest
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	bannedUserId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	if err := readJSON(r, &banPayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	banType := storage.CommunityBanTypeBan
+	if banPayload.BanType != "" {
+		banType = storage.CommunityBanType(banPayload.BanType)
+		if banType != storage.CommunityBanTypeBan && banType != storage.CommunityBanTypeMute {
+			writeJSONError(w, "ban type should be ban or mute", http.StatusBadRequest)
+			return
+		}
+	}
+
+	banReason := strings.TrimSpace(banPayload.BanReason)
+	if banReason == "" {
+		writeJSONError(w, "ban reason is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(banReason) > MAX_COMMUNITY_BAN_REASON_LENGTH {
+		writeJSONError(w, "ban reason should be at most "+strconv.Itoa(MAX_COMMUNITY_BAN_REASON_LENGTH)+" characters", http.StatusBadRequest)
+		return
+	}
+
+	var expiration *time.Time
+	if banPayload.ExpiresInHours != nil {
+
+		if *banPayload.ExpiresInHours < 1 || *banPayload.ExpiresInHours > MAX_COMMUNITY_BAN_EXPIRY_HOURS {
+			writeJSONError(w, "expires in hours should be between 1 and "+strconv.Itoa(MAX_COMMUNITY_BAN_EXPIRY_HOURS), http.StatusBadRequest)
+			return
+		}
+
+		banExpiration := time.Now().Add(time.Duration(*banPayload.ExpiresInHours) * time.Hour)
+		expiration = &banExpiration
+	}
+
+	if bannedUserId == userId {
+		writeJSONError(w, "cannot ban yourself", http.StatusBadRequest)
+		return
+	}
+
+	bannedUser, err := h.storage.Users.GetUserById(bannedUserId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	canBan, bannedUserRole, err := h.canBanCommunityUser(userId, bannedUser.Id, communityId, false)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canBan {
+		writeJSONError(w, "cannot ban a "+string(bannedUserRole)+" of community", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.storage.CommunityBans.CreateCommunityBan(communityId, bannedUser.Id, userId, banType, banReason, expiration, &storage.AuditLogEntry{
+		ActorId:     userId,
+		CommunityId: &communityId,
+		Action:      storage.AuditActionUserBan,
+		TargetType:  storage.AuditTargetUser,
+		TargetId:    bannedUser.Id,
+		Reason:      banReason,
+	})
+	if err != nil {
+		log.Printf("failed to create community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                 `json:"success"`
+		Message string               `json:"message"`
+		Ban     storage.CommunityBan `json:"ban"`
+	}
+
+	message := "banned user"
+	if banType == storage.CommunityBanTypeMute {
+		message = "muted user"
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: message, Ban: *ban}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// moderator only, lifts a ban or mute. a lifted ban doesn't give back the membership
+func (h *Handler) UnbanCommunityUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	bannedUserId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ban, err := h.storage.CommunityBans.GetActiveCommunityBan(bannedUserId, communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "ban not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.CommunityBans.DeleteCommunityBan(bannedUserId, communityId, &storage.AuditLogEntry{
+		ActorId:     userId,
+		CommunityId: &communityId,
+		Action:      storage.AuditActionUserUnban,
+		TargetType:  storage.AuditTargetUser,
+		TargetId:    bannedUserId,
+		Before:      ban,
+		Reason:      strings.TrimSpace(r.URL.Query().Get("reason")),
+	}); err != nil {
+		log.Printf("failed to delete community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "lifted ban"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
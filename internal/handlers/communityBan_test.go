@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+)
+
+func TestCommunityBanMessage(t *testing.T) {
+
+	expiration := "2025-01-02T03:04:05Z"
+
+	tests := []struct {
+		name string
+		ban  storage.CommunityBan
+		want string
+	}{
+		{
+			name: "permanent ban",
+			ban:  storage.CommunityBan{BanType: storage.CommunityBanTypeBan, BanReason: "spam"},
+			want: "user is banned from community, reason: spam",
+		},
+		{
+			name: "temporary ban",
+			ban:  storage.CommunityBan{BanType: storage.CommunityBanTypeBan, BanReason: "spam", Expiration: &expiration},
+			want: "user is banned from community until 2025-01-02T03:04:05Z, reason: spam",
+		},
+		{
+			name: "permanent mute",
+			ban:  storage.CommunityBan{BanType: storage.CommunityBanTypeMute, BanReason: "flaming"},
+			want: "user is muted in community, reason: flaming",
+		},
+		{
+			name: "temporary mute",
+			ban:  storage.CommunityBan{BanType: storage.CommunityBanTypeMute, BanReason: "flaming", Expiration: &expiration},
+			want: "user is muted in community until 2025-01-02T03:04:05Z, reason: flaming",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if got := communityBanMessage(&tt.ban); got != tt.want {
+				t.Errorf("communityBanMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+const (
+	testCommunityId = 1
+	testOwnerId     = 1
+	testMemberId    = 2
+)
+
+// newBanTestHandler has a public community that testMemberId belongs to, with ban of testMemberId when set
+func newBanTestHandler(ban *storage.CommunityBan) *Handler {
+
+	bans := map[communityMember]*storage.CommunityBan{}
+	if ban != nil {
+		bans[communityMember{userId: testMemberId, communityId: testCommunityId}] = ban
+	}
+
+	return &Handler{
+		storage: &storage.Storage{
+			Users: &fakeUserRepo{users: map[int]*storage.User{
+				testOwnerId:  {Id: testOwnerId},
+				testMemberId: {Id: testMemberId},
+			}},
+			Communities: &fakeCommunityRepo{
+				communities: map[int]*storage.Community{
+					testCommunityId: {Id: testCommunityId, CommunityOwnerId: testOwnerId, CommunityVisibility: storage.CommunityVisibilityPublic},
+				},
+				roles: map[communityMember]storage.CommunityRole{
+					{userId: testMemberId, communityId: testCommunityId}: storage.CommunityRoleMember,
+				},
+			},
+			CommunityBans: &fakeCommunityBanRepo{bans: bans},
+		},
+	}
+}
+
+func TestGetBlockingCommunityBan(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		ban          *storage.CommunityBan
+		includeMutes bool
+		wantBlocked  bool
+	}{
+		{name: "not banned", ban: nil, includeMutes: true, wantBlocked: false},
+		{name: "banned", ban: &storage.CommunityBan{BanType: storage.CommunityBanTypeBan}, includeMutes: false, wantBlocked: true},
+		{name: "banned including mutes", ban: &storage.CommunityBan{BanType: storage.CommunityBanTypeBan}, includeMutes: true, wantBlocked: true},
+		{name: "muted", ban: &storage.CommunityBan{BanType: storage.CommunityBanTypeMute}, includeMutes: false, wantBlocked: false},
+		{name: "muted including mutes", ban: &storage.CommunityBan{BanType: storage.CommunityBanTypeMute}, includeMutes: true, wantBlocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			h := newBanTestHandler(tt.ban)
+
+			ban, err := h.getBlockingCommunityBan(testMemberId, testCommunityId, tt.includeMutes)
+			if err != nil {
+				t.Fatalf("getBlockingCommunityBan() error = %v", err)
+			}
+
+			if (ban != nil) != tt.wantBlocked {
+				t.Errorf("getBlockingCommunityBan() = %+v, want blocked %v", ban, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestCreateCommunityPostHandlerBans(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		ban         *storage.CommunityBan
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "banned",
+			ban:         &storage.CommunityBan{BanType: storage.CommunityBanTypeBan, BanReason: "spam"},
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "user is banned from community, reason: spam",
+		},
+		{
+			name:        "muted",
+			ban:         &storage.CommunityBan{BanType: storage.CommunityBanTypeMute, BanReason: "flaming"},
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "user is muted in community, reason: flaming",
+		},
+		{
+			// gets past the ban check to the payload validation
+			name:        "not banned",
+			ban:         nil,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "title and content required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			h := newBanTestHandler(tt.ban)
+
+			w := httptest.NewRecorder()
+			r := newTestRequest("POST", "/", `{"post_title":"","post_content":""}`, testMemberId, "communityId", "1")
+
+			h.CreateCommunityPostHandler(w, r)
+
+			var response struct {
+				Success bool   `json:"success"`
+				Message string `json:"message"`
+			}
+
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if w.Code != tt.wantStatus || response.Message != tt.wantMessage {
+				t.Errorf("CreateCommunityPostHandler() = %d %q, want %d %q", w.Code, response.Message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	COMMUNITY_INVITE_CODE_BYTES       = 8
+	MAX_COMMUNITY_INVITE_USES         = 1000
+	MAX_COMMUNITY_INVITE_EXPIRY_HOURS = 24 * 30
+	MAX_JOIN_REQUESTS_LIMIT           = 50
+)
+
+type CreateCommunityInviteRequest struct {
+	MaxUses        *int `json:"max_uses"`         // unlimited when not set
+	ExpiresInHours *int `json:"expires_in_hours"` // never expires when not set
+}
+
+// moderator only, lists pending join requests of a restricted or private community
+func (h *Handler) GetCommunityJoinRequestsHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_JOIN_REQUESTS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	joinRequests, err := h.storage.Communities.GetCommunityJoinRequests(communityId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get community join requests: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalJoinRequestsCount, err := h.storage.Communities.GetCommunityJoinRequestsCount(communityId)
+	if err != nil {
+		log.Printf("failed to get community join requests count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalJoinRequestsCount) / float64(limit)))
+
+	// moderators only need to know who is asking
+	for i := range joinRequests {
+		joinRequests[i].User.Email = ""
+	}
+
+	type Response struct {
+		Success      bool                                   `json:"success"`
+		JoinRequests []storage.CommunityJoinRequestWithUser `json:"join_requests"`
+		NoOfPages    int                                    `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, JoinRequests: joinRequests, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) ApproveJoinRequestHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	requestUserId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	userCommunity, err := h.storage.Communities.ApproveJoinRequest(requestUserId, communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "join request not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to approve join request: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success       bool                  `json:"success"`
+		Message       string                `json:"message"`
+		UserCommunity storage.UserCommunity `json:"user_community"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "approved join request", UserCommunity: *userCommunity}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) RejectJoinRequestHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	requestUserId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.storage.Communities.GetJoinRequest(requestUserId, communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "join request not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.Communities.DeleteJoinRequest(requestUserId, communityId); err != nil {
+		log.Printf("failed to reject join request: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "rejected join request"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// moderator only, creates an invite link that lets users skip the join request
+func (h *Handler) CreateCommunityInviteHandler(w http.ResponseWriter, r *http.Request) {
+
+	var createInvitePayload CreateCommunityInviteRequest
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	if err := readJSON(r, &createInvitePayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if createInvitePayload.MaxUses != nil && (*createInvitePayload.MaxUses < 1 || *createInvitePayload.MaxUses > MAX_COMMUNITY_INVITE_USES) {
+		writeJSONError(w, "max uses should be between 1 and "+strconv.Itoa(MAX_COMMUNITY_INVITE_USES), http.StatusBadRequest)
+		return
+	}
+
+	var expiration *time.Time
+	if createInvitePayload.ExpiresInHours != nil {
+
+		if *createInvitePayload.ExpiresInHours < 1 || *createInvitePayload.ExpiresInHours > MAX_COMMUNITY_INVITE_EXPIRY_HOURS {
+			writeJSONError(w, "expires in hours should be between 1 and "+strconv.Itoa(MAX_COMMUNITY_INVITE_EXPIRY_HOURS), http.StatusBadRequest)
+			return
+		}
+
+		inviteExpiration := time.Now().Add(time.Duration(*createInvitePayload.ExpiresInHours) * time.Hour)
+		expiration = &inviteExpiration
+	}
+
+	inviteCode := generateToken(COMMUNITY_INVITE_CODE_BYTES)
+
+	invite, err := h.storage.Communities.CreateCommunityInvite(inviteCode, communityId, userId, createInvitePayload.MaxUses, expiration)
+	if err != nil {
+		log.Printf("failed to create community invite: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                    `json:"success"`
+		Message string                  `json:"message"`
+		Invite  storage.CommunityInvite `json:"invite"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "created invite", Invite: *invite}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetCommunityInvitesHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	invites, err := h.storage.Communities.GetCommunityInvites(communityId)
+	if err != nil {
+		log.Printf("failed to get community invites: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                      `json:"success"`
+		Invites []storage.CommunityInvite `json:"invites"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Invites: invites}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) DeleteCommunityInviteHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	inviteId, err := strconv.Atoi(chi.URLParam(r, "inviteId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param inviteId", http.StatusBadRequest)
+		return
+	}
+
+	invite, err := h.storage.Communities.GetCommunityInviteById(inviteId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invite not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if invite.CommunityId != communityId {
+		writeJSONError(w, "invite not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.storage.Communities.DeleteCommunityInviteById(invite.Id); err != nil {
+		log.Printf("failed to delete community invite: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "deleted invite"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// joins the invite's community directly, whatever its visibility
+func (h *Handler) AcceptCommunityInviteHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	inviteCode := chi.URLParam(r, "inviteCode")
+
+	invite, err := h.storage.Communities.GetUsableCommunityInvite(inviteCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invite not found or expired", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	role, err := h.storage.Communities.GetCommunityRole(user.Id, invite.CommunityId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if role != "" {
+		writeJSONError(w, "user is already part of community", http.StatusBadRequest)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, invite.CommunityId, false)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	userCommunity, err := h.storage.Communities.AcceptCommunityInvite(invite.InviteCode, user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// used up or expired since it was looked up
+			writeJSONError(w, "invite not found or expired", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to accept community invite: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success       bool                  `json:"success"`
+		Message       string                `json:"message"`
+		UserCommunity storage.UserCommunity `json:"user_community"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "joined community", UserCommunity: *userCommunity}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
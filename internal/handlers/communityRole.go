@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// roles are ranked, a role grants everything the roles below it can do
+var communityRoleRanks = map[storage.CommunityRole]int{
+	storage.CommunityRoleMember:    1,
+	storage.CommunityRoleModerator: 2,
+	storage.CommunityRoleOwner:     3,
+}
+
+// reports whether the user's role in the community is at least minRole
+func (h *Handler) hasCommunityRole(userId int, communityId int, minRole storage.CommunityRole) (bool, error) {
+
+	role, err := h.storage.Communities.GetCommunityRole(userId, communityId)
+	if err != nil {
+		return false, err
+	}
+
+	return communityRoleRanks[role] >= communityRoleRanks[minRole], nil
+}
+
+// private communities can only be read by their members, userId is 0 for anonymous requests
+func (h *Handler) canViewCommunity(userId int, community *storage.Community) (bool, error) {
+
+	if community.CommunityVisibility != storage.CommunityVisibilityPrivate {
+		return true, nil
+	}
+
+	if userId == 0 {
+		return false, nil
+	}
+
+	return h.hasCommunityRole(userId, community.Id, storage.CommunityRoleMember)
+}
+
+// reports whether the user can read a post and its comments, and so interact with them
+func (h *Handler) canViewPost(userId int, postCommunityId int) (bool, error) {
+
+	community, err := h.storage.Communities.GetCommunityById(postCommunityId)
+	if err != nil {
+		return false, err
+	}
+
+	return h.canViewCommunity(userId, community)
+}
+
+// restricted and private communities only let their members comment, vote and react, anyone else
+// can at most read them
+func (h *Handler) canParticipateInCommunity(userId int, community *storage.Community) (bool, error) {
+
+	if community.CommunityVisibility == storage.CommunityVisibilityPublic {
+		return true, nil
+	}
+
+	if userId == 0 {
+		return false, nil
+	}
+
+	return h.hasCommunityRole(userId, community.Id, storage.CommunityRoleMember)
+}
+
+// reports whether the user can comment on, vote on and react to a post and its comments
+func (h *Handler) canParticipateInPost(userId int, postCommunityId int) (bool, error) {
+
+	community, err := h.storage.Communities.GetCommunityById(postCommunityId)
+	if err != nil {
+		return false, err
+	}
+
+	return h.canParticipateInCommunity(userId, community)
+}
+
+// CommunityRoleMiddleware only lets through users whose role in the {communityId} community is at least minRole,
+// it will only be used after auth middleware
+func (h *Handler) CommunityRoleMiddleware(minRole storage.CommunityRole) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			userId, ok := r.Context().Value(AuthUserId).(int)
+			if !ok {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+			if err != nil {
+				writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+				return
+			}
+
+			isAllowed, err := h.hasCommunityRole(userId, communityId, minRole)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					writeJSONError(w, "community not found", http.StatusNotFound)
+					return
+				} else {
+					log.Printf("failed to get community role: %v\n", err)
+					writeJSONError(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if !isAllowed {
+				writeJSONError(w, "user is not a "+string(minRole)+" of community", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *Handler) CommunityOwnerMiddleware(next http.Handler) http.Handler {
+	return h.CommunityRoleMiddleware(storage.CommunityRoleOwner)(next)
+}
+
+func (h *Handler) CommunityModeratorMiddleware(next http.Handler) http.Handler {
+	return h.CommunityRoleMiddleware(storage.CommunityRoleModerator)(next)
+}
+
+func (h *Handler) GetCommunityModeratorsHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	community, err := h.storage.Communities.GetCommunityById(communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "community not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	moderators, err := h.storage.Communities.GetCommunityModerators(community.Id)
+	if err != nil {
+		log.Printf("failed to get community moderators: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// moderators are listed publicly, don't expose their emails
+	for i := range moderators {
+		moderators[i].Email = ""
+	}
+
+	type Response struct {
+		Success    bool           `json:"success"`
+		Moderators []storage.User `json:"moderators"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Moderators: moderators}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// owner only, the appointed user has to be a member of the community
+func (h *Handler) AddCommunityModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateCommunityMemberRole(w, r, storage.CommunityRoleModerator)
+}
+
+// owner only, the moderator stays a member of the community
+func (h *Handler) RemoveCommunityModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateCommunityMemberRole(w, r, storage.CommunityRoleMember)
+}
+
+func (h *Handler) updateCommunityMemberRole(w http.ResponseWriter, r *http.Request, role storage.CommunityRole) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	memberId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	auditAction := storage.AuditActionModeratorAdd
+	if role != storage.CommunityRoleModerator {
+		auditAction = storage.AuditActionModeratorRemove
+	}
+
+	userCommunity, err := h.storage.Communities.UpdateCommunityMemberRole(memberId, communityId, role, &storage.AuditLogEntry{
+		ActorId:     userId,
+		CommunityId: &communityId,
+		Action:      auditAction,
+		TargetType:  storage.AuditTargetUser,
+		TargetId:    memberId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user is not a member of community", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to update community member role: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success       bool                  `json:"success"`
+		Message       string                `json:"message"`
+		UserCommunity storage.UserCommunity `json:"user_community"`
+	}
+
+	message := "appointed moderator"
+	if role != storage.CommunityRoleModerator {
+		message = "removed moderator"
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: message, UserCommunity: *userCommunity}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
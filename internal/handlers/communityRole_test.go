@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+)
+
+const testOutsiderId = 3
+
+// newVisibilityTestHandler has a community with the given visibility owned by testOwnerId, that
+// testMemberId belongs to and testOutsiderId doesn't
+func newVisibilityTestHandler(visibility storage.CommunityVisibility) *Handler {
+
+	return &Handler{
+		storage: &storage.Storage{
+			Communities: &fakeCommunityRepo{
+				communities: map[int]*storage.Community{
+					testCommunityId: {Id: testCommunityId, CommunityOwnerId: testOwnerId, CommunityVisibility: visibility},
+				},
+				roles: map[communityMember]storage.CommunityRole{
+					{userId: testMemberId, communityId: testCommunityId}: storage.CommunityRoleMember,
+				},
+			},
+			Posts:     &fakePostRepo{},
+			Reactions: &fakeReactionRepo{},
+		},
+	}
+}
+
+var communityVisibilityTests = []struct {
+	name        string
+	visibility  storage.CommunityVisibility
+	userId      int
+	wantVisible bool
+}{
+	{name: "public to anonymous", visibility: storage.CommunityVisibilityPublic, userId: 0, wantVisible: true},
+	{name: "public to outsider", visibility: storage.CommunityVisibilityPublic, userId: testOutsiderId, wantVisible: true},
+	{name: "restricted to anonymous", visibility: storage.CommunityVisibilityRestricted, userId: 0, wantVisible: true},
+	{name: "restricted to outsider", visibility: storage.CommunityVisibilityRestricted, userId: testOutsiderId, wantVisible: true},
+	{name: "private to anonymous", visibility: storage.CommunityVisibilityPrivate, userId: 0, wantVisible: false},
+	{name: "private to outsider", visibility: storage.CommunityVisibilityPrivate, userId: testOutsiderId, wantVisible: false},
+	{name: "private to member", visibility: storage.CommunityVisibilityPrivate, userId: testMemberId, wantVisible: true},
+	{name: "private to owner", visibility: storage.CommunityVisibilityPrivate, userId: testOwnerId, wantVisible: true},
+}
+
+func TestCanViewCommunity(t *testing.T) {
+
+	for _, tt := range communityVisibilityTests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			h := newVisibilityTestHandler(tt.visibility)
+
+			community, _ := h.storage.Communities.GetCommunityById(testCommunityId)
+
+			isVisible, err := h.canViewCommunity(tt.userId, community)
+			if err != nil {
+				t.Fatalf("canViewCommunity() error = %v", err)
+			}
+
+			if isVisible != tt.wantVisible {
+				t.Errorf("canViewCommunity() = %v, want %v", isVisible, tt.wantVisible)
+			}
+		})
+	}
+}
+
+func TestGetCommunityPostsHandlerVisibility(t *testing.T) {
+
+	for _, tt := range communityVisibilityTests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			h := newVisibilityTestHandler(tt.visibility)
+
+			w := httptest.NewRecorder()
+			r := newTestRequest("GET", "/", "", tt.userId, "communityId", strconv.Itoa(testCommunityId))
+
+			h.GetCommunityPostsHandler(w, r)
+
+			wantStatus := http.StatusOK
+			if !tt.wantVisible {
+				wantStatus = http.StatusForbidden
+			}
+
+			if w.Code != wantStatus {
+				t.Errorf("GetCommunityPostsHandler() status = %d, want %d, body %s", w.Code, wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCanParticipateInCommunity(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		visibility  storage.CommunityVisibility
+		userId      int
+		wantAllowed bool
+	}{
+		{name: "public to anonymous", visibility: storage.CommunityVisibilityPublic, userId: 0, wantAllowed: true},
+		{name: "public to outsider", visibility: storage.CommunityVisibilityPublic, userId: testOutsiderId, wantAllowed: true},
+		{name: "restricted to anonymous", visibility: storage.CommunityVisibilityRestricted, userId: 0, wantAllowed: false},
+		{name: "restricted to outsider", visibility: storage.CommunityVisibilityRestricted, userId: testOutsiderId, wantAllowed: false},
+		{name: "restricted to member", visibility: storage.CommunityVisibilityRestricted, userId: testMemberId, wantAllowed: true},
+		{name: "restricted to owner", visibility: storage.CommunityVisibilityRestricted, userId: testOwnerId, wantAllowed: true},
+		{name: "private to outsider", visibility: storage.CommunityVisibilityPrivate, userId: testOutsiderId, wantAllowed: false},
+		{name: "private to member", visibility: storage.CommunityVisibilityPrivate, userId: testMemberId, wantAllowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			h := newVisibilityTestHandler(tt.visibility)
+
+			community, _ := h.storage.Communities.GetCommunityById(testCommunityId)
+
+			canParticipate, err := h.canParticipateInCommunity(tt.userId, community)
+			if err != nil {
+				t.Fatalf("canParticipateInCommunity() error = %v", err)
+			}
+
+			if canParticipate != tt.wantAllowed {
+				t.Errorf("canParticipateInCommunity() = %v, want %v", canParticipate, tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// the fakes embed the repository interfaces they stand in for, calling a method a fake doesn't
+// implement panics on the nil interface
+
+type fakeUserRepo struct {
+	storage.UserRepository
+	users map[int]*storage.User
+}
+
+func (f *fakeUserRepo) GetUserById(id int) (*storage.User, error) {
+
+	user, ok := f.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return user, nil
+}
+
+// communityMember is a user's membership of a community
+type communityMember struct {
+	userId      int
+	communityId int
+}
+
+type fakeCommunityRepo struct {
+	storage.CommunityRepository
+	communities map[int]*storage.Community
+	roles       map[communityMember]storage.CommunityRole
+}
+
+func (f *fakeCommunityRepo) GetCommunityById(communityId int) (*storage.Community, error) {
+
+	community, ok := f.communities[communityId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return community, nil
+}
+
+func (f *fakeCommunityRepo) GetCommunityRole(userId int, communityId int) (storage.CommunityRole, error) {
+
+	community, ok := f.communities[communityId]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+
+	if community.CommunityOwnerId == userId {
+		return storage.CommunityRoleOwner, nil
+	}
+
+	return f.roles[communityMember{userId: userId, communityId: communityId}], nil
+}
+
+func (f *fakeCommunityRepo) CheckCommunityForUser(userId int, communityId int) (bool, error) {
+
+	if _, ok := f.roles[communityMember{userId: userId, communityId: communityId}]; !ok {
+		return false, sql.ErrNoRows
+	}
+
+	return true, nil
+}
+
+// bans holds the active bans only, expired ones are left out by the query like GetActiveCommunityBan does
+type fakeCommunityBanRepo struct {
+	storage.CommunityBanRepository
+	bans map[communityMember]*storage.CommunityBan
+}
+
+func (f *fakeCommunityBanRepo) GetActiveCommunityBan(userId int, communityId int) (*storage.CommunityBan, error) {
+
+	ban, ok := f.bans[communityMember{userId: userId, communityId: communityId}]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return ban, nil
+}
+
+// newTestRequest builds a request as the router would hand it to a handler, userId is 0 for anonymous
+// requests and urlParams are name, value pairs
+func newTestRequest(method string, target string, body string, userId int, urlParams ...string) *http.Request {
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+
+	routeCtx := chi.NewRouteContext()
+	for i := 0; i+1 < len(urlParams); i += 2 {
+		routeCtx.URLParams.Add(urlParams[i], urlParams[i+1])
+	}
+
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+	if userId != 0 {
+		ctx = context.WithValue(ctx, AuthUserId, userId)
+	}
+
+	return r.WithContext(ctx)
+}
+
+// fakePostRepo lists posts of a community without any posts
+type fakePostRepo struct {
+	storage.PostRepository
+}
+
+func (f *fakePostRepo) GetCommunityPostsByCursor(communityId int, sortBy storage.SortByStr, search string, cursor *storage.PostCursor, limit int) ([]storage.PostWithMetaData, *storage.PostCursor, error) {
+	return []storage.PostWithMetaData{}, nil, nil
+}
+
+func (f *fakePostRepo) GetCommunityPostsCount(communityId int, search string) (int, error) {
+	return 0, nil
+}
+
+func (f *fakePostRepo) SetPostsViewerState(userId int, posts []storage.PostWithMetaData) error {
+	return nil
+}
+
+type fakeReactionRepo struct {
+	storage.ReactionRepository
+}
+
+func (f *fakeReactionRepo) SetPostsReactions(userId int, posts []storage.PostWithMetaData) error {
+	return nil
+}
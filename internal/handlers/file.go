@@ -120,7 +120,7 @@ func (h *Handler) UserImageFileUploadHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	uploadedObjectUrl := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", os.Getenv("AWS_S3_BUCKET"), os.Getenv("AWS_REGION"), awsS3ObjectKey)
+	uploadedObjectUrl := s3ObjectUrl(awsS3ObjectKey)
 
 	type Response struct {
 		Success bool   `json:"success"`
@@ -132,3 +132,12 @@ func (h *Handler) UserImageFileUploadHandler(w http.ResponseWriter, r *http.Requ
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
+
+func s3ObjectUrl(key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", os.Getenv("AWS_S3_BUCKET"), os.Getenv("AWS_REGION"), key)
+}
+
+// url prefix of every file uploaded by the user through UserImageFileUploadHandler
+func userUploadsUrlPrefix(userId int) string {
+	return s3ObjectUrl(fmt.Sprintf("%s/userId-%d/", "uploads", userId))
+}
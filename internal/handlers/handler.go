@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/dhruv15803/go-community-platform/internal/storage"
 	"github.com/redis/go-redis/v9"
-	"net/http"
 )
 
 type Handler struct {
@@ -56,3 +64,107 @@ func readJSON(r *http.Request, v interface{}) error {
 	decoder.DisallowUnknownFields()
 	return decoder.Decode(v)
 }
+
+// parsePagination reads the ?page and ?limit query params, page defaults to 1 and limit to defaultLimit.
+// the returned error is the message to respond with when either one is out of bounds
+func parsePagination(r *http.Request, defaultLimit int, maxLimit int) (int, int, error) {
+
+	page := 1
+	limit := defaultLimit
+	var err error
+
+	if r.URL.Query().Get("page") != "" {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			return 0, 0, errors.New("invalid query param page")
+		}
+	}
+
+	if r.URL.Query().Get("limit") != "" {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, errors.New("limit should be between 1 and " + strconv.Itoa(maxLimit))
+		}
+	}
+
+	return page, limit, nil
+}
+
+// pushes a job onto a worker queue, on failure the job is moved to the queue's dlq
+func (h *Handler) pushJob(queue string, jobJson []byte) bool {
+
+	maxRetries := 3
+	for i := 0; i < maxRetries; i++ {
+
+		if err := h.rdb.LPush(context.Background(), queue, string(jobJson)).Err(); err != nil {
+			log.Printf("failed to push job into %s, attempt %d : %v\n", queue, i+1, err)
+			continue
+		}
+
+		return true
+	}
+
+	type JobFailureDetail struct {
+		TimeStamp time.Time       `json:"timestamp"`
+		Job       json.RawMessage `json:"job"`
+	}
+
+	jobFailureJson, err := json.Marshal(JobFailureDetail{TimeStamp: time.Now(), Job: jobJson})
+	if err == nil {
+		_ = h.rdb.LPush(context.Background(), queue+":dlq", string(jobFailureJson)).Err()
+	}
+
+	return false
+}
+
+// S3CleanupJob removes every object under Prefix except KeepKeys, or only Keys when they are set
+type S3CleanupJob struct {
+	UserId   int      `json:"user_id"`
+	Prefix   string   `json:"prefix"`
+	KeepKeys []string `json:"keep_keys"`
+	Keys     []string `json:"keys,omitempty"`
+}
+
+// pushUploadsCleanup queues the removal of uploads nothing uses anymore from s3, only the urls under the
+// uploads of userId are removed since a post can use an image uploaded by someone else
+func (h *Handler) pushUploadsCleanup(userId int, unusedUrls []string) {
+
+	uploadsPrefix := fmt.Sprintf("uploads/userId-%d/", userId)
+
+	var keys []string
+	for _, url := range unusedUrls {
+		if i := strings.Index(url, uploadsPrefix); i != -1 {
+			keys = append(keys, url[i:])
+		}
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+
+	s3CleanupJobJson, err := json.Marshal(S3CleanupJob{UserId: userId, Prefix: uploadsPrefix, Keys: keys})
+	if err == nil && !h.pushJob("queue:s3:cleanup", s3CleanupJobJson) {
+		log.Printf("failed to push s3 cleanup job for uploads of user %d\n", userId)
+	}
+}
+
+// counts a request against key and reports whether it is within limit for the current fixed window
+func (h *Handler) allowRequest(key string, limit int64, window time.Duration) (bool, error) {
+
+	ctx := context.Background()
+
+	// the window starts with the SET, in the same transaction as the INCR so the counter can't
+	// be left without an expiry
+	var count *redis.IntCmd
+
+	_, err := h.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(ctx, key, 0, window)
+		count = pipe.Incr(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count.Val() <= limit, nil
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		query     string
+		wantPage  int
+		wantLimit int
+		wantErr   string
+	}{
+		{name: "defaults", query: "", wantPage: 1, wantLimit: 10},
+		{name: "page and limit", query: "?page=3&limit=25", wantPage: 3, wantLimit: 25},
+		{name: "max limit", query: "?limit=50", wantPage: 1, wantLimit: 50},
+		{name: "page 0", query: "?page=0", wantErr: "invalid query param page"},
+		{name: "negative page", query: "?page=-1", wantErr: "invalid query param page"},
+		{name: "page not a number", query: "?page=two", wantErr: "invalid query param page"},
+		{name: "limit 0", query: "?limit=0", wantErr: "limit should be between 1 and 50"},
+		{name: "negative limit", query: "?limit=-10", wantErr: "limit should be between 1 and 50"},
+		{name: "limit over max", query: "?limit=51", wantErr: "limit should be between 1 and 50"},
+		{name: "limit not a number", query: "?limit=ten", wantErr: "limit should be between 1 and 50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := httptest.NewRequest("GET", "/"+tt.query, nil)
+
+			page, limit, err := parsePagination(r, 10, 50)
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parsePagination() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parsePagination() error = %v", err)
+			}
+
+			if page != tt.wantPage || limit != tt.wantLimit {
+				t.Errorf("parsePagination() = %d, %d, want %d, %d", page, limit, tt.wantPage, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -13,12 +13,29 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	MAX_POSTS_LIMIT     = 50
+	MAX_REVISIONS_LIMIT = 50
+)
+
 type CreatePostRequest struct {
 	PostTitle     string   `json:"post_title"`
 	PostContent   string   `json:"post_content"`
 	PostImageUrls []string `json:"post_image_urls"`
 }
 
+// vote value is 1 for an upvote, -1 for a downvote and 0 to remove the vote
+type VoteRequest struct {
+	VoteValue *int `json:"vote_value"`
+}
+
+// fields left out are kept, post_image_urls replaces all of the post's images when set
+type UpdatePostRequest struct {
+	PostTitle     *string   `json:"post_title"`
+	PostContent   *string   `json:"post_content"`
+	PostImageUrls *[]string `json:"post_image_urls"`
+}
+
 // create community post handler
 func (h *Handler) CreateCommunityPostHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -68,6 +85,18 @@ func (h *Handler) CreateCommunityPostHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	ban, err := h.getBlockingCommunityBan(user.Id, community.Id, true)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
 	var createPostPayload CreatePostRequest
 
 	if err := readJSON(r, &createPostPayload); err != nil {
@@ -177,23 +206,50 @@ func (h *Handler) DeleteCommunityPostHandler(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	// post has to be part of community , if yes then delete only if the user is community owner or post owner
+	// post has to be part of community , if yes then delete only if the user is post owner or a community moderator (owner included)
 
 	if post.PostCommunityId != community.Id {
 		writeJSONError(w, "post is not part of community", http.StatusBadRequest)
 		return
 	}
 
-	if user.Id != post.PostOwnerId && user.Id != community.CommunityOwnerId {
-		writeJSONError(w, "user unauthorized to delete community post", http.StatusForbidden)
-		return
+	// moderators removing someone else's post is recorded in the audit log along with ?reason=
+	var audit *storage.AuditLogEntry
+
+	if user.Id != post.PostOwnerId {
+
+		isModerator, err := h.hasCommunityRole(user.Id, community.Id, storage.CommunityRoleModerator)
+		if err != nil {
+			log.Printf("failed to get community role: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if !isModerator {
+			writeJSONError(w, "user unauthorized to delete community post", http.StatusForbidden)
+			return
+		}
+
+		audit = &storage.AuditLogEntry{
+			ActorId:     user.Id,
+			CommunityId: &community.Id,
+			Action:      storage.AuditActionPostDelete,
+			TargetType:  storage.AuditTargetPost,
+			TargetId:    post.Id,
+			Before:      post,
+			Reason:      strings.TrimSpace(r.URL.Query().Get("reason")),
+		}
 	}
 
-	if err = h.storage.Posts.DeletePostById(postId); err != nil {
+	unusedImageUrls, err := h.storage.Posts.DeletePostById(postId, audit)
+	if err != nil {
+		log.Printf("failed to delete post: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	h.pushUploadsCleanup(post.PostOwnerId, unusedImageUrls)
+
 	type Response struct {
 		Success bool   `json:"success"`
 		Message string `json:"message"`
@@ -204,7 +260,7 @@ func (h *Handler) DeleteCommunityPostHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
-// toggle like post handler
+// toggle like post handler, likes are upvotes
 func (h *Handler) TogglePostLikeHandler(w http.ResponseWriter, r *http.Request) {
 
 	userId, ok := r.Context().Value(AuthUserId).(int)
@@ -241,17 +297,42 @@ func (h *Handler) TogglePostLikeHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// check if post like by user already exists
-	// yes -> remove like
-	//	 no -> creat like
+	canParticipate, err := h.canParticipateInPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
 
-	isPostLiked, _ := h.storage.Posts.CheckPostLike(user.Id, post.Id)
+	if !canParticipate {
+		writeJSONError(w, "only community members can participate", http.StatusForbidden)
+		return
+	}
 
-	if isPostLiked {
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, false)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
 
-		//	 remove post like
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
 
-		if err := h.storage.Posts.RemovePostLike(user.Id, post.Id); err != nil {
+	// a like is an upvote, liking a post the user already upvoted removes the upvote
+	postVote, err := h.storage.Posts.GetPostVote(user.Id, post.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	isPostLiked := postVote != nil && postVote.VoteValue == storage.VoteUp
+
+	if isPostLiked {
+
+		if err := h.storage.Posts.RemovePostVote(user.Id, post.Id); err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 
 			return
@@ -267,7 +348,7 @@ func (h *Handler) TogglePostLikeHandler(w http.ResponseWriter, r *http.Request)
 		}
 
 	} else {
-		postLike, err := h.storage.Posts.CreatePostLike(user.Id, post.Id)
+		postVote, err := h.storage.Posts.VotePost(user.Id, post.Id, storage.VoteUp)
 		if err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
@@ -276,14 +357,124 @@ func (h *Handler) TogglePostLikeHandler(w http.ResponseWriter, r *http.Request)
 		type Response struct {
 			Success  bool             `json:"success"`
 			Message  string           `json:"message"`
-			PostLike storage.PostLike `json:"post_like"`
+			PostVote storage.PostVote `json:"post_vote"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "liked post", PostVote: *postVote}, http.StatusCreated); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+	}
+
+}
+
+// sets the user's vote on a post, a vote value of 0 removes it
+func (h *Handler) VotePostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	var votePayload VoteRequest
+
+	if err := readJSON(r, &votePayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if votePayload.VoteValue == nil || (*votePayload.VoteValue != storage.VoteUp && *votePayload.VoteValue != storage.VoteDown && *votePayload.VoteValue != 0) {
+		writeJSONError(w, "vote value should be 1, -1 or 0", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(postId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	canParticipate, err := h.canParticipateInPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canParticipate {
+		writeJSONError(w, "only community members can participate", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, false)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	if *votePayload.VoteValue == 0 {
+
+		if err := h.storage.Posts.RemovePostVote(user.Id, post.Id); err != nil {
+			log.Printf("failed to remove post vote: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
 		}
 
-		if err := writeJSON(w, Response{Success: true, Message: "liked post", PostLike: *postLike}, http.StatusCreated); err != nil {
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "removed vote"}, http.StatusOK); err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		}
+		return
 	}
 
+	postVote, err := h.storage.Posts.VotePost(user.Id, post.Id, *votePayload.VoteValue)
+	if err != nil {
+		log.Printf("failed to vote post: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success  bool             `json:"success"`
+		Message  string           `json:"message"`
+		PostVote storage.PostVote `json:"post_vote"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "voted post", PostVote: *postVote}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
 }
 
 func (h *Handler) TogglePostBookmarkHandler(w http.ResponseWriter, r *http.Request) {
@@ -322,6 +513,18 @@ func (h *Handler) TogglePostBookmarkHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	isVisible, err := h.canViewPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
 	isPostBookmarked, _ := h.storage.Posts.CheckPostBookmark(user.Id, post.Id)
 
 	if isPostBookmarked {
@@ -363,8 +566,10 @@ func (h *Handler) TogglePostBookmarkHandler(w http.ResponseWriter, r *http.Reque
 
 // ?page=1&limit=10&sortBy="hot"
 // no auth required
-// ?page=1&limit=10&sortBy="new"
+// ?limit=10&sortBy="new"&cursor=...
 // ?page=1&limit=10&sortBy="top"&search="goasdkajsda"
+// search matches post titles and content, with the same syntax as /api/search.
+// without ?page the posts are paginated by cursor, see postsPage
 
 func (h *Handler) GetCommunityPostsHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -385,29 +590,30 @@ func (h *Handler) GetCommunityPostsHandler(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	// private communities are only readable by their members
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	isVisible, err := h.canViewCommunity(userId, community)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
 	var page int
 	var limit int
 	var search string
 	var sortBy storage.SortByStr
 
-	if r.URL.Query().Get("page") == "" {
-		page = 1
-	} else {
-		page, err = strconv.Atoi(r.URL.Query().Get("page"))
-		if err != nil {
-			writeJSONError(w, "invalid request param page", http.StatusBadRequest)
-			return
-		}
-	}
-
-	if r.URL.Query().Get("limit") == "" {
-		limit = 10
-	} else {
-		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
-		if err != nil {
-			writeJSONError(w, "invalid request param limit", http.StatusBadRequest)
-			return
-		}
+	page, limit, err = parsePagination(r, 10, MAX_POSTS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	if r.URL.Query().Get("sortBy") == "" {
@@ -422,31 +628,50 @@ func (h *Handler) GetCommunityPostsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	cursor, err := parsePostsCursor(r, sortBy)
+	if err != nil {
+		writeJSONError(w, "invalid query param cursor", http.StatusBadRequest)
+		return
+	}
+
 	skip := page*limit - limit
 
-	posts, err := h.storage.Posts.GetCommunityPosts(community.Id, skip, limit, sortBy, search)
+	var posts []storage.PostWithMetaData
+	var nextCursor *storage.PostCursor
+
+	if usesPages(r) {
+		posts, err = h.storage.Posts.GetCommunityPosts(community.Id, skip, limit, sortBy, search)
+	} else {
+		posts, nextCursor, err = h.storage.Posts.GetCommunityPostsByCursor(community.Id, sortBy, search, cursor, limit)
+	}
+
 	if err != nil {
 		log.Printf("failed to get posts: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalPostsCount, err := h.storage.Posts.GetCommunityPostsCount(community.Id, search)
-	if err != nil {
-		log.Printf("failed to get posts count: %v\n", err)
+	if err := h.setPostsViewerState(userId, posts); err != nil {
+		log.Printf("failed to get posts viewer state: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	noOfPages := int(math.Ceil(float64(totalPostsCount) / float64(limit)))
+	var noOfPages *int
 
-	type Response struct {
-		Success   bool                       `json:"success"`
-		Posts     []storage.PostWithMetaData `json:"posts"`
-		NoOfPages int                        `json:"no_of_pages"`
+	if cursor == nil {
+
+		totalPostsCount, err := h.storage.Posts.GetCommunityPostsCount(community.Id, search)
+		if err != nil {
+			log.Printf("failed to get posts count: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		noOfPages = postsNoOfPages(totalPostsCount, limit)
 	}
 
-	if err := writeJSON(w, Response{Success: true, Posts: posts, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+	if err := writeJSON(w, newPostsPage(posts, noOfPages, nextCursor), http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
@@ -474,24 +699,10 @@ func (h *Handler) GetUserPostsFeedHandler(w http.ResponseWriter, r *http.Request
 	var limit int
 	var sortBy storage.SortByStr
 
-	if r.URL.Query().Get("page") == "" {
-		page = 1
-	} else {
-		page, err = strconv.Atoi(r.URL.Query().Get("page"))
-		if err != nil {
-			writeJSONError(w, "invalid request param page", http.StatusBadRequest)
-			return
-		}
-	}
-
-	if r.URL.Query().Get("limit") == "" {
-		limit = 10
-	} else {
-		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
-		if err != nil {
-			writeJSONError(w, "invalid request param limit", http.StatusBadRequest)
-			return
-		}
+	page, limit, err = parsePagination(r, 10, MAX_POSTS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	if r.URL.Query().Get("sortBy") == "" {
@@ -500,32 +711,52 @@ func (h *Handler) GetUserPostsFeedHandler(w http.ResponseWriter, r *http.Request
 		sortBy = storage.SortByStr(r.URL.Query().Get("sortBy"))
 	}
 
+	cursor, err := parsePostsCursor(r, sortBy)
+	if err != nil {
+		writeJSONError(w, "invalid query param cursor", http.StatusBadRequest)
+		return
+	}
+
 	skip := page*limit - limit
 	//fetchPostsFromTopNCommunitiesThatUserJoinedByNoOfMembers
 	n := 3
-	posts, err := h.storage.Posts.GetUserPostsFeed(user.Id, n, skip, limit, sortBy)
+
+	var posts []storage.PostWithMetaData
+	var nextCursor *storage.PostCursor
+
+	if usesPages(r) {
+		posts, err = h.storage.Posts.GetUserPostsFeed(user.Id, n, skip, limit, sortBy)
+	} else {
+		posts, nextCursor, err = h.storage.Posts.GetUserPostsFeedByCursor(user.Id, n, sortBy, cursor, limit)
+	}
+
 	if err != nil {
 		log.Printf("failed to get posts: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalPostsCount, err := h.storage.Posts.GetUserPostsFeedCount(user.Id, n)
-	if err != nil {
-		log.Printf("failed to get posts count: %v\n", err)
+	if err := h.setPostsViewerState(user.Id, posts); err != nil {
+		log.Printf("failed to get posts viewer state: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	noOfPages := int(math.Ceil(float64(totalPostsCount) / float64(limit)))
+	var noOfPages *int
 
-	type Response struct {
-		Success   bool                       `json:"success"`
-		Posts     []storage.PostWithMetaData `json:"posts"`
-		NoOfPages int                        `json:"no_of_pages"`
+	if cursor == nil {
+
+		totalPostsCount, err := h.storage.Posts.GetUserPostsFeedCount(user.Id, n)
+		if err != nil {
+			log.Printf("failed to get posts count: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		noOfPages = postsNoOfPages(totalPostsCount, limit)
 	}
 
-	if err := writeJSON(w, Response{Success: true, Posts: posts, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+	if err := writeJSON(w, newPostsPage(posts, noOfPages, nextCursor), http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -538,59 +769,373 @@ func (h *Handler) GetPostsFeedHandler(w http.ResponseWriter, r *http.Request) {
 	var sortBy storage.SortByStr
 	var err error
 
-	if r.URL.Query().Get("page") == "" {
-		page = 1
+	page, limit, err = parsePagination(r, 10, MAX_POSTS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("sortBy") == "" {
+		sortBy = storage.SortByRelevance
 	} else {
-		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		sortBy = storage.SortByStr(r.URL.Query().Get("sortBy"))
+	}
+
+	cursor, err := parsePostsCursor(r, sortBy)
+	if err != nil {
+		writeJSONError(w, "invalid query param cursor", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+	n := 3 // post from  top 3 communities of the application
+
+	var posts []storage.PostWithMetaData
+	var nextCursor *storage.PostCursor
+
+	if usesPages(r) {
+		posts, err = h.storage.Posts.GetPostsFeed(n, skip, limit, sortBy)
+	} else {
+		posts, nextCursor, err = h.storage.Posts.GetPostsFeedByCursor(n, sortBy, cursor, limit)
+	}
+
+	if err != nil {
+		log.Printf("failed to get posts: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	if err := h.setPostsViewerState(userId, posts); err != nil {
+		log.Printf("failed to get posts viewer state: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var noOfPages *int
+
+	if cursor == nil {
+
+		totalPostsCount, err := h.storage.Posts.GetPostsFeedCount(n)
 		if err != nil {
-			writeJSONError(w, "invalid request param page", http.StatusBadRequest)
+			log.Printf("failed to get posts count: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
+
+		noOfPages = postsNoOfPages(totalPostsCount, limit)
 	}
 
-	if r.URL.Query().Get("limit") == "" {
-		limit = 10
-	} else {
-		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
-		if err != nil {
-			writeJSONError(w, "invalid request param limit", http.StatusBadRequest)
+	if err := writeJSON(w, newPostsPage(posts, noOfPages, nextCursor), http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+}
+
+// postsPage is a page of a feed. feeds are paginated by cursor unless ?page is given: next_cursor continues
+// after the last post (null at the end of the feed) and is passed back as ?cursor with the same sortBy. hot feeds
+// keep the ranking of their first page. no_of_pages is only counted for ?page and the first page of a cursor
+type postsPage struct {
+	Success    bool                       `json:"success"`
+	Posts      []storage.PostWithMetaData `json:"posts"`
+	NoOfPages  *int                       `json:"no_of_pages,omitempty"`
+	NextCursor *string                    `json:"next_cursor"`
+}
+
+func newPostsPage(posts []storage.PostWithMetaData, noOfPages *int, nextCursor *storage.PostCursor) postsPage {
+
+	page := postsPage{Success: true, Posts: posts, NoOfPages: noOfPages}
+
+	if nextCursor != nil {
+		encodedCursor := nextCursor.Encode()
+		page.NextCursor = &encodedCursor
+	}
+
+	return page
+}
+
+func postsNoOfPages(totalPostsCount int, limit int) *int {
+
+	noOfPages := int(math.Ceil(float64(totalPostsCount) / float64(limit)))
+	return &noOfPages
+}
+
+func usesPages(r *http.Request) bool {
+	return r.URL.Query().Get("page") != ""
+}
+
+// parsePostsCursor decodes ?cursor, which has to come from a feed with the same sortBy. it's nil when
+// the feed is paginated by page or starts from its first post
+func parsePostsCursor(r *http.Request, sortBy storage.SortByStr) (*storage.PostCursor, error) {
+
+	if usesPages(r) || r.URL.Query().Get("cursor") == "" {
+		return nil, nil
+	}
+
+	cursor, err := storage.DecodePostCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor.SortBy != sortBy {
+		return nil, storage.ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// setPostsViewerState sets the reactions on posts along with the viewer's vote, bookmark flag and own reactions,
+// a zero userId is an anonymous viewer
+func (h *Handler) setPostsViewerState(userId int, posts []storage.PostWithMetaData) error {
+
+	if err := h.storage.Reactions.SetPostsReactions(userId, posts); err != nil {
+		return err
+	}
+
+	if userId == 0 {
+		return nil
+	}
+
+	return h.storage.Posts.SetPostsViewerState(userId, posts)
+}
+
+// no auth required, a post along with its community. authenticated viewers also get whether they liked
+// and bookmarked it
+func (h *Handler) GetPostHandler(w http.ResponseWriter, r *http.Request) {
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostWithMetaDataById(postId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to get post: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	if r.URL.Query().Get("sortBy") == "" {
-		sortBy = storage.SortByRelevance
-	} else {
-		sortBy = storage.SortByStr(r.URL.Query().Get("sortBy"))
+	community, err := h.storage.Communities.GetCommunityById(post.PostCommunityId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	skip := page*limit - limit
-	n := 3 // post from  top 3 communities of the application
-	posts, err := h.storage.Posts.GetPostsFeed(n, skip, limit, sortBy)
+	// private communities are only readable by their members
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	isVisible, err := h.canViewCommunity(userId, community)
 	if err != nil {
-		log.Printf("failed to get posts: %v\n", err)
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
+	posts := []storage.PostWithMetaData{*post}
+
+	if err := h.setPostsViewerState(userId, posts); err != nil {
+		log.Printf("failed to get posts viewer state: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalPostsCount, err := h.storage.Posts.GetPostsFeedCount(n)
+	posts[0].PostOwner.Email = ""
+
+	type Response struct {
+		Success   bool                     `json:"success"`
+		Post      storage.PostWithMetaData `json:"post"`
+		Community storage.Community        `json:"community"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Post: posts[0], Community: *community}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// only the author can edit a post, the replaced version is kept as a revision
+func (h *Handler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(postId)
 	if err != nil {
-		log.Printf("failed to get posts count: %v\n", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if post.PostOwnerId != user.Id {
+		writeJSONError(w, "only the author can edit post", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, true)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	noOfPages := int(math.Ceil(float64(totalPostsCount) / float64(limit)))
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	var updatePostPayload UpdatePostRequest
+
+	if err := readJSON(r, &updatePostPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if updatePostPayload.PostTitle == nil && updatePostPayload.PostContent == nil && updatePostPayload.PostImageUrls == nil {
+		writeJSONError(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	postTitle := post.PostTitle
+	postContent := post.PostContent
+	var postImageUrls []string
+
+	if updatePostPayload.PostTitle != nil {
+		postTitle = strings.TrimSpace(*updatePostPayload.PostTitle)
+	}
+
+	if updatePostPayload.PostContent != nil {
+		postContent = strings.TrimSpace(*updatePostPayload.PostContent)
+	}
+
+	if postTitle == "" || postContent == "" {
+		writeJSONError(w, "title and content required", http.StatusBadRequest)
+		return
+	}
+
+	if updatePostPayload.PostImageUrls != nil {
+		// an empty list removes every image
+		postImageUrls = *updatePostPayload.PostImageUrls
+	}
+
+	updatedPost, err := h.storage.Posts.UpdatePostById(post.Id, postTitle, postContent, postImageUrls, user.Id)
+	if err != nil {
+		log.Printf("failed to update post: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
 
 	type Response struct {
-		Success   bool                       `json:"success"`
-		Posts     []storage.PostWithMetaData `json:"posts"`
-		NoOfPages int                        `json:"no_of_pages"`
+		Success bool                   `json:"success"`
+		Message string                 `json:"message"`
+		Post    storage.PostWithImages `json:"post"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "updated post", Post: *updatedPost}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// optional, only needed to read posts of private communities
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(postId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	if err := writeJSON(w, Response{Success: true, Posts: posts, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+	isVisible, err := h.canViewPost(userId, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_REVISIONS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	revisions, err := h.storage.Posts.GetPostRevisions(post.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get post revisions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalRevisionsCount, err := h.storage.Posts.GetPostRevisionsCount(post.Id)
+	if err != nil {
+		log.Printf("failed to get post revisions count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalRevisionsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                   `json:"success"`
+		Revisions []storage.PostRevision `json:"revisions"`
+		NoOfPages int                    `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Revisions: revisions, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
 }
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+)
+
+func TestGetCommunityPostsHandlerLimit(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "cursor with limit 0", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "page with limit 0", query: "?page=1&limit=0", wantStatus: http.StatusBadRequest},
+		{name: "cursor with negative limit", query: "?limit=-1", wantStatus: http.StatusBadRequest},
+		{name: "page with negative limit", query: "?page=1&limit=-1", wantStatus: http.StatusBadRequest},
+		{name: "limit over max", query: "?limit=" + strconv.Itoa(MAX_POSTS_LIMIT+1), wantStatus: http.StatusBadRequest},
+		{name: "page 0", query: "?page=0&limit=10", wantStatus: http.StatusBadRequest},
+		{name: "cursor with max limit", query: "?limit=" + strconv.Itoa(MAX_POSTS_LIMIT), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			h := newVisibilityTestHandler(storage.CommunityVisibilityPublic)
+
+			w := httptest.NewRecorder()
+			r := newTestRequest("GET", "/"+tt.query, "", 0, "communityId", strconv.Itoa(testCommunityId))
+
+			h.GetCommunityPostsHandler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GetCommunityPostsHandler() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
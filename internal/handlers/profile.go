@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const MAX_PROFILE_LIMIT = 50
+
+// public profiles are only shown for verified users, deleted users have no username and are never found
+func (h *Handler) getProfileUser(username string) (*storage.User, error) {
+
+	user, err := h.storage.Users.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsVerified {
+		return nil, sql.ErrNoRows
+	}
+
+	return user, nil
+}
+
+func (h *Handler) GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, err := h.getProfileUser(chi.URLParam(r, "username"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to get user: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	profile, err := h.storage.Users.GetPublicUserProfile(user.Id)
+	if err != nil {
+		log.Printf("failed to get user profile: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                      `json:"success"`
+		User    storage.PublicUserProfile `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, User: *profile}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ?page=1&limit=10
+func (h *Handler) GetUserProfilePostsHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, err := h.getProfileUser(chi.URLParam(r, "username"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to get user: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_PROFILE_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	posts, err := h.storage.Posts.GetUserPosts(user.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get user posts: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// profile pages are public, don't expose the owner's email
+	for i := range posts {
+		posts[i].PostOwner.Email = ""
+	}
+
+	viewerId, _ := r.Context().Value(AuthUserId).(int)
+
+	if err := h.setPostsViewerState(viewerId, posts); err != nil {
+		log.Printf("failed to get posts viewer state: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalPostsCount, err := h.storage.Posts.GetUserPostsCount(user.Id)
+	if err != nil {
+		log.Printf("failed to get user posts count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalPostsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                       `json:"success"`
+		Posts     []storage.PostWithMetaData `json:"posts"`
+		NoOfPages int                        `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Posts: posts, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ?page=1&limit=10
+func (h *Handler) GetUserProfileCommentsHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, err := h.getProfileUser(chi.URLParam(r, "username"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to get user: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_PROFILE_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	comments, err := h.storage.PostComments.GetUserComments(user.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get user comments: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// profile pages are public, don't expose the owner's email
+	for i := range comments {
+		comments[i].CommentOwner.Email = ""
+	}
+
+	viewerId, _ := r.Context().Value(AuthUserId).(int)
+
+	if err := h.storage.Reactions.SetCommentsReactions(viewerId, commentsReactionTargets(comments)); err != nil {
+		log.Printf("failed to get comments reactions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalCommentsCount, err := h.storage.PostComments.GetUserCommentsCount(user.Id)
+	if err != nil {
+		log.Printf("failed to get user comments count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalCommentsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                              `json:"success"`
+		Comments  []storage.PostCommentWithMetaData `json:"comments"`
+		NoOfPages int                               `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Comments: comments, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ?page=1&limit=10
+func (h *Handler) GetUserProfileCommunitiesHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, err := h.getProfileUser(chi.URLParam(r, "username"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to get user: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_PROFILE_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	communities, err := h.storage.Communities.GetUserCommunities(user.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get user communities: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalCommunitiesCount, err := h.storage.Communities.GetUserCommunitiesCount(user.Id)
+	if err != nil {
+		log.Printf("failed to get user communities count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalCommunitiesCount) / float64(limit)))
+
+	type Response struct {
+		Success     bool                `json:"success"`
+		Communities []storage.Community `json:"communities"`
+		NoOfPages   int                 `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Communities: communities, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
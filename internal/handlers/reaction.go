@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	MAX_COMMUNITY_REACTIONS = 20
+	MAX_REACTION_LENGTH     = 32 // in bytes, emojis with modifiers take several code points
+)
+
+type ReactionRequest struct {
+	Reaction string `json:"reaction"`
+}
+
+type CommunityReactionsRequest struct {
+	Reactions []string `json:"reactions"`
+}
+
+// commentsReactionTargets points at each of the comments so their reactions can be set in place
+func commentsReactionTargets(comments []storage.PostCommentWithMetaData) []*storage.PostCommentWithMetaData {
+
+	targets := make([]*storage.PostCommentWithMetaData, len(comments))
+	for i := range comments {
+		targets[i] = &comments[i]
+	}
+
+	return targets
+}
+
+// commentTreeReactionTargets points at every comment in the tree, replies included
+func commentTreeReactionTargets(nodes []storage.CommentTreeNode) []*storage.PostCommentWithMetaData {
+
+	var targets []*storage.PostCommentWithMetaData
+	for i := range nodes {
+		targets = append(targets, &nodes[i].PostCommentWithMetaData)
+		targets = append(targets, commentTreeReactionTargets(nodes[i].Replies)...)
+	}
+
+	return targets
+}
+
+// reacting with a reaction the user already gave removes it, the reaction has to be allowed in the post's community
+func (h *Handler) TogglePostReactionHandler(w http.ResponseWriter, r *http.Request) {
+
+	var reactionPayload ReactionRequest
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	if err := readJSON(r, &reactionPayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	reaction := strings.TrimSpace(reactionPayload.Reaction)
+	if reaction == "" {
+		writeJSONError(w, "reaction is required", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(postId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	canParticipate, err := h.canParticipateInPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canParticipate {
+		writeJSONError(w, "only community members can participate", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, false)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	isPostReacted, err := h.storage.Reactions.CheckPostReaction(user.Id, post.Id, reaction)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// removing a reaction stays possible after the community stops allowing it
+	if isPostReacted {
+
+		if err := h.storage.Reactions.RemovePostReaction(user.Id, post.Id, reaction); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "post reaction removed"}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	allowedReactions, err := h.storage.Reactions.GetCommunityAllowedReactions(post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to get community allowed reactions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !slices.Contains(allowedReactions, reaction) {
+		writeJSONError(w, "reaction is not allowed in community", http.StatusBadRequest)
+		return
+	}
+
+	postReaction, err := h.storage.Reactions.CreatePostReaction(user.Id, post.Id, reaction)
+	if err != nil {
+		log.Printf("failed to create post reaction: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success      bool                 `json:"success"`
+		Message      string               `json:"message"`
+		PostReaction storage.PostReaction `json:"post_reaction"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "post reacted", PostReaction: *postReaction}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// reacting with a reaction the user already gave removes it, the reaction has to be allowed in the comment's community
+func (h *Handler) ToggleCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+
+	var reactionPayload ReactionRequest
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	commentId, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param commentId", http.StatusBadRequest)
+		return
+	}
+
+	if err := readJSON(r, &reactionPayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	reaction := strings.TrimSpace(reactionPayload.Reaction)
+	if reaction == "" {
+		writeJSONError(w, "reaction is required", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.storage.PostComments.GetPostCommentById(commentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post comment not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if comment.CommentDeletedAt != nil {
+		writeJSONError(w, "cannot react to a deleted comment", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(comment.PostId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	canParticipate, err := h.canParticipateInPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canParticipate {
+		writeJSONError(w, "only community members can participate", http.StatusForbidden)
+		return
+	}
+
+	ban, err := h.getBlockingCommunityBan(user.Id, post.PostCommunityId, false)
+	if err != nil {
+		log.Printf("failed to check community ban: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ban != nil {
+		writeJSONError(w, communityBanMessage(ban), http.StatusForbidden)
+		return
+	}
+
+	isCommentReacted, err := h.storage.Reactions.CheckCommentReaction(user.Id, comment.Id, reaction)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if isCommentReacted {
+
+		if err := h.storage.Reactions.RemoveCommentReaction(user.Id, comment.Id, reaction); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "comment reaction removed"}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	allowedReactions, err := h.storage.Reactions.GetCommunityAllowedReactions(post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to get community allowed reactions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !slices.Contains(allowedReactions, reaction) {
+		writeJSONError(w, "reaction is not allowed in community", http.StatusBadRequest)
+		return
+	}
+
+	commentReaction, err := h.storage.Reactions.CreateCommentReaction(user.Id, comment.Id, reaction)
+	if err != nil {
+		log.Printf("failed to create comment reaction: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success         bool                        `json:"success"`
+		Message         string                      `json:"message"`
+		CommentReaction storage.PostCommentReaction `json:"comment_reaction"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "comment reacted", CommentReaction: *commentReaction}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// no auth required, the reactions that can be given to posts and comments in the community
+func (h *Handler) GetCommunityReactionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	allowedReactions, err := h.storage.Reactions.GetCommunityAllowedReactions(communityId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "community not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to get community allowed reactions: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success   bool     `json:"success"`
+		Reactions []string `json:"reactions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Reactions: allowedReactions}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// moderators only, an empty list turns reactions off in the community and a null list restores the default set
+func (h *Handler) UpdateCommunityReactionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	var reactionsPayload CommunityReactionsRequest
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	if err := readJSON(r, &reactionsPayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(reactionsPayload.Reactions) > MAX_COMMUNITY_REACTIONS {
+		writeJSONError(w, "a community can allow at most "+strconv.Itoa(MAX_COMMUNITY_REACTIONS)+" reactions", http.StatusBadRequest)
+		return
+	}
+
+	var allowedReactions []string
+	if reactionsPayload.Reactions != nil {
+		allowedReactions = make([]string, 0, len(reactionsPayload.Reactions))
+	}
+
+	for _, reaction := range reactionsPayload.Reactions {
+
+		reaction = strings.TrimSpace(reaction)
+
+		if reaction == "" || len(reaction) > MAX_REACTION_LENGTH {
+			writeJSONError(w, "reactions should be between 1 and "+strconv.Itoa(MAX_REACTION_LENGTH)+" bytes", http.StatusBadRequest)
+			return
+		}
+
+		if slices.Contains(allowedReactions, reaction) {
+			writeJSONError(w, "reactions should be unique", http.StatusBadRequest)
+			return
+		}
+
+		allowedReactions = append(allowedReactions, reaction)
+	}
+
+	updatedReactions, err := h.storage.Reactions.UpdateCommunityAllowedReactions(communityId, allowedReactions)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "community not found", http.StatusNotFound)
+			return
+		} else {
+			log.Printf("failed to update community allowed reactions: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success   bool     `json:"success"`
+		Message   string   `json:"message"`
+		Reactions []string `json:"reactions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "community reactions updated", Reactions: updatedReactions}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
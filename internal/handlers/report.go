@@ -0,0 +1,550 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	MAX_REPORT_DETAILS_LENGTH = 1000
+	REPORT_WINDOW             = time.Hour
+	REPORT_WINDOW_LIMIT       = 20
+	MAX_REPORTS_LIMIT         = 50
+)
+
+// actions a moderator can take when resolving a report
+const (
+	REPORT_ACTION_REMOVE_CONTENT = "remove_content"
+	REPORT_ACTION_DISMISS        = "dismiss"
+	REPORT_ACTION_BAN_AUTHOR     = "ban_author" // also removes the reported content
+)
+
+var reportReasons = map[storage.ReportReason]bool{
+	storage.ReportReasonSpam:           true,
+	storage.ReportReasonHarassment:     true,
+	storage.ReportReasonHateSpeech:     true,
+	storage.ReportReasonViolence:       true,
+	storage.ReportReasonSexualContent:  true,
+	storage.ReportReasonMisinformation: true,
+	storage.ReportReasonOther:          true,
+}
+
+type ReportRequest struct {
+	ReportReason  string `json:"report_reason"`
+	ReportDetails string `json:"report_details"`
+}
+
+type ResolveReportRequest struct {
+	Action            string `json:"action"`
+	Reason            string `json:"reason"`               // recorded in the audit log
+	BanReason         string `json:"ban_reason"`           // defaults to the reason, then the report reason
+	BanExpiresInHours *int   `json:"ban_expires_in_hours"` // permanent when not set
+}
+
+// validates the report payload and rate limits reporting, writes the error response itself
+func (h *Handler) readReportRequest(w http.ResponseWriter, r *http.Request, userId int) (*ReportRequest, bool) {
+
+	var reportPayload ReportRequest
+
+	if err := readJSON(r, &reportPayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if !reportReasons[storage.ReportReason(reportPayload.ReportReason)] {
+		writeJSONError(w, "invalid report reason", http.StatusBadRequest)
+		return nil, false
+	}
+
+	reportPayload.ReportDetails = strings.TrimSpace(reportPayload.ReportDetails)
+	if len(reportPayload.ReportDetails) > MAX_REPORT_DETAILS_LENGTH {
+		writeJSONError(w, "report details should be at most "+strconv.Itoa(MAX_REPORT_DETAILS_LENGTH)+" characters", http.StatusBadRequest)
+		return nil, false
+	}
+
+	isAllowed, err := h.allowRequest("report:"+strconv.Itoa(userId), REPORT_WINDOW_LIMIT, REPORT_WINDOW)
+	if err != nil {
+		log.Printf("failed to rate limit report: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if !isAllowed {
+		writeJSONError(w, "too many reports, try again later", http.StatusTooManyRequests)
+		return nil, false
+	}
+
+	return &reportPayload, true
+}
+
+func (h *Handler) ReportPostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.Posts.GetPostById(postId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	isVisible, err := h.canViewPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
+	if post.PostOwnerId == user.Id {
+		writeJSONError(w, "cannot report your own post", http.StatusBadRequest)
+		return
+	}
+
+	isReported, err := h.storage.Reports.CheckOpenPostReport(user.Id, post.Id)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if isReported {
+		writeJSONError(w, "post is already reported", http.StatusBadRequest)
+		return
+	}
+
+	reportPayload, ok := h.readReportRequest(w, r, user.Id)
+	if !ok {
+		return
+	}
+
+	report, err := h.storage.Reports.CreateReport(user.Id, post.PostCommunityId, &post.Id, nil, storage.ReportReason(reportPayload.ReportReason), reportPayload.ReportDetails)
+	if err != nil {
+		log.Printf("failed to create post report: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		Report  storage.Report `json:"report"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "reported post", Report: *report}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) ReportCommentHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	commentId, err := strconv.Atoi(chi.URLParam(r, "commentId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param commentId", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.storage.PostComments.GetPostCommentById(commentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "comment not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	post, err := h.storage.Posts.GetPostById(comment.PostId)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	isVisible, err := h.canViewPost(user.Id, post.PostCommunityId)
+	if err != nil {
+		log.Printf("failed to check community access: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isVisible {
+		writeJSONError(w, "community is private", http.StatusForbidden)
+		return
+	}
+
+	if comment.CommentOwnerId == user.Id {
+		writeJSONError(w, "cannot report your own comment", http.StatusBadRequest)
+		return
+	}
+
+	if comment.CommentDeletedAt != nil {
+		writeJSONError(w, "cannot report a deleted comment", http.StatusBadRequest)
+		return
+	}
+
+	isReported, err := h.storage.Reports.CheckOpenCommentReport(user.Id, comment.Id)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if isReported {
+		writeJSONError(w, "comment is already reported", http.StatusBadRequest)
+		return
+	}
+
+	reportPayload, ok := h.readReportRequest(w, r, user.Id)
+	if !ok {
+		return
+	}
+
+	report, err := h.storage.Reports.CreateReport(user.Id, post.PostCommunityId, nil, &comment.Id, storage.ReportReason(reportPayload.ReportReason), reportPayload.ReportDetails)
+	if err != nil {
+		log.Printf("failed to create comment report: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		Report  storage.Report `json:"report"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "reported comment", Report: *report}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// moderator only, the community's report queue. ?status=open|resolved, defaults to open
+func (h *Handler) GetCommunityReportsHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	h.getReports(w, r, &communityId)
+}
+
+// admin only, the site-wide report queue. ?status=open|resolved, defaults to open
+func (h *Handler) GetReportsHandler(w http.ResponseWriter, r *http.Request) {
+	h.getReports(w, r, nil)
+}
+
+func (h *Handler) getReports(w http.ResponseWriter, r *http.Request, communityId *int) {
+
+	page, limit, err := parsePagination(r, 10, MAX_REPORTS_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := storage.ReportStatusOpen
+	if r.URL.Query().Get("status") != "" {
+		status = storage.ReportStatus(r.URL.Query().Get("status"))
+		if status != storage.ReportStatusOpen && status != storage.ReportStatusResolved {
+			writeJSONError(w, "invalid query param status", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	var reports []storage.Report
+	var totalReportsCount int
+
+	if communityId != nil {
+		reports, err = h.storage.Reports.GetCommunityReports(*communityId, status, skip, limit)
+		if err == nil {
+			totalReportsCount, err = h.storage.Reports.GetCommunityReportsCount(*communityId, status)
+		}
+	} else {
+		reports, err = h.storage.Reports.GetReports(status, skip, limit)
+		if err == nil {
+			totalReportsCount, err = h.storage.Reports.GetReportsCount(status)
+		}
+	}
+
+	if err != nil {
+		log.Printf("failed to get reports: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalReportsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool             `json:"success"`
+		Reports   []storage.Report `json:"reports"`
+		NoOfPages int              `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Reports: reports, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// moderator only, resolves a report of the {communityId} community
+func (h *Handler) ResolveCommunityReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	communityId, err := strconv.Atoi(chi.URLParam(r, "communityId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param communityId", http.StatusBadRequest)
+		return
+	}
+
+	h.resolveReport(w, r, &communityId)
+}
+
+// admin only, resolves a report of any community
+func (h *Handler) ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	h.resolveReport(w, r, nil)
+}
+
+// resolves the {reportId} report, communityId is nil when a site admin is resolving it
+func (h *Handler) resolveReport(w http.ResponseWriter, r *http.Request, communityId *int) {
+
+	var resolveReportPayload ResolveReportRequest
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	reportId, err := strconv.Atoi(chi.URLParam(r, "reportId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param reportId", http.StatusBadRequest)
+		return
+	}
+
+	if err := readJSON(r, &resolveReportPayload); err != nil {
+		writeJSONError(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.storage.Reports.GetReportById(reportId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "report not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if communityId != nil && report.CommunityId != *communityId {
+		writeJSONError(w, "report not found", http.StatusNotFound)
+		return
+	}
+
+	if report.ReportStatus != storage.ReportStatusOpen {
+		writeJSONError(w, "report is already resolved", http.StatusBadRequest)
+		return
+	}
+
+	if report.PostId == nil && report.CommentId == nil {
+		writeJSONError(w, "reported content not found", http.StatusNotFound)
+		return
+	}
+
+	var resolution storage.ReportResolution
+	var removeContent bool
+	var authorBan *storage.ReportAuthorBan
+
+	switch resolveReportPayload.Action {
+	case REPORT_ACTION_DISMISS:
+		resolution = storage.ReportResolutionDismissed
+	case REPORT_ACTION_REMOVE_CONTENT:
+		resolution = storage.ReportResolutionContentRemoved
+		removeContent = true
+	case REPORT_ACTION_BAN_AUTHOR:
+		resolution = storage.ReportResolutionAuthorBanned
+		removeContent = true
+	default:
+		writeJSONError(w, "action should be remove_content, dismiss or ban_author", http.StatusBadRequest)
+		return
+	}
+
+	// removing the content is recorded like a moderator deleting it
+	var authorId int
+	var removeContentAudit *storage.AuditLogEntry
+
+	if removeContent {
+
+		removeContentAudit = &storage.AuditLogEntry{
+			ActorId:     userId,
+			CommunityId: &report.CommunityId,
+			Reason:      strings.TrimSpace(resolveReportPayload.Reason),
+		}
+
+		if report.CommentId != nil {
+			comment, err := h.storage.PostComments.GetPostCommentById(*report.CommentId)
+			if err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			authorId = comment.CommentOwnerId
+
+			removeContentAudit.Action = storage.AuditActionCommentDelete
+			removeContentAudit.TargetType = storage.AuditTargetComment
+			removeContentAudit.TargetId = comment.Id
+			removeContentAudit.Before = comment
+		} else {
+			post, err := h.storage.Posts.GetPostById(*report.PostId)
+			if err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			authorId = post.PostOwnerId
+
+			removeContentAudit.Action = storage.AuditActionPostDelete
+			removeContentAudit.TargetType = storage.AuditTargetPost
+			removeContentAudit.TargetId = post.Id
+			removeContentAudit.Before = post
+		}
+	}
+
+	if resolution == storage.ReportResolutionAuthorBanned {
+
+		if authorId == userId {
+			writeJSONError(w, "cannot ban yourself", http.StatusBadRequest)
+			return
+		}
+
+		canBan, authorRole, err := h.canBanCommunityUser(userId, authorId, report.CommunityId, communityId == nil)
+		if err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if !canBan {
+			writeJSONError(w, "cannot ban a "+string(authorRole)+" of community", http.StatusForbidden)
+			return
+		}
+
+		banReason := strings.TrimSpace(resolveReportPayload.BanReason)
+		if banReason == "" {
+			banReason = strings.TrimSpace(resolveReportPayload.Reason)
+		}
+		if banReason == "" {
+			banReason = string(report.ReportReason)
+		}
+
+		if len(banReason) > MAX_COMMUNITY_BAN_REASON_LENGTH {
+			writeJSONError(w, "ban reason should be at most "+strconv.Itoa(MAX_COMMUNITY_BAN_REASON_LENGTH)+" characters", http.StatusBadRequest)
+			return
+		}
+
+		var expiration *time.Time
+		if resolveReportPayload.BanExpiresInHours != nil {
+
+			if *resolveReportPayload.BanExpiresInHours < 1 || *resolveReportPayload.BanExpiresInHours > MAX_COMMUNITY_BAN_EXPIRY_HOURS {
+				writeJSONError(w, "ban expires in hours should be between 1 and "+strconv.Itoa(MAX_COMMUNITY_BAN_EXPIRY_HOURS), http.StatusBadRequest)
+				return
+			}
+
+			banExpiration := time.Now().Add(time.Duration(*resolveReportPayload.BanExpiresInHours) * time.Hour)
+			expiration = &banExpiration
+		}
+
+		authorBan = &storage.ReportAuthorBan{
+			UserId:     authorId,
+			BanReason:  banReason,
+			Expiration: expiration,
+			Audit: &storage.AuditLogEntry{
+				ActorId:     userId,
+				CommunityId: &report.CommunityId,
+				Action:      storage.AuditActionUserBan,
+				TargetType:  storage.AuditTargetUser,
+				TargetId:    authorId,
+				Reason:      banReason,
+			},
+		}
+	}
+
+	resolvedReport, unusedImageUrls, err := h.storage.Reports.ResolveReport(report, userId, resolution, removeContentAudit, authorBan, &storage.AuditLogEntry{
+		ActorId:     userId,
+		CommunityId: &report.CommunityId,
+		Action:      storage.AuditActionReportResolve,
+		TargetType:  storage.AuditTargetReport,
+		TargetId:    report.Id,
+		Before:      report,
+		Reason:      strings.TrimSpace(resolveReportPayload.Reason),
+	})
+	if err != nil {
+		log.Printf("failed to resolve report: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.pushUploadsCleanup(authorId, unusedImageUrls)
+
+	type Response struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		Report  storage.Report `json:"report"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "resolved report", Report: *resolvedReport}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dhruv15803/go-community-platform/internal/storage"
+)
+
+const (
+	MAX_SEARCH_QUERY_LENGTH = 200
+	MAX_SEARCH_LIMIT        = 50
+)
+
+// no auth required, ?q=go+"worker pools"&type=post,comment&page=1&limit=10
+// searches posts, comments and communities (all of them without ?type), best matches first.
+// results from private communities are only returned to their members
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+
+	searchQuery := strings.TrimSpace(r.URL.Query().Get("q"))
+	if searchQuery == "" {
+		writeJSONError(w, "query param q is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(searchQuery) > MAX_SEARCH_QUERY_LENGTH {
+		writeJSONError(w, "search query should be at most "+strconv.Itoa(MAX_SEARCH_QUERY_LENGTH)+" characters", http.StatusBadRequest)
+		return
+	}
+
+	searchTypes := storage.SearchTypes
+
+	if r.URL.Query().Get("type") != "" {
+
+		searchTypes = nil
+
+		for _, searchTypeStr := range strings.Split(r.URL.Query().Get("type"), ",") {
+
+			searchType := storage.SearchType(strings.TrimSpace(searchTypeStr))
+
+			if !slices.Contains(storage.SearchTypes, searchType) {
+				writeJSONError(w, "type should be post, comment or community", http.StatusBadRequest)
+				return
+			}
+
+			if !slices.Contains(searchTypes, searchType) {
+				searchTypes = append(searchTypes, searchType)
+			}
+		}
+	}
+
+	page, limit, err := parsePagination(r, 10, MAX_SEARCH_LIMIT)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	userId, _ := r.Context().Value(AuthUserId).(int)
+
+	results, err := h.storage.Search.Search(searchQuery, searchTypes, userId, skip, limit)
+	if err != nil {
+		log.Printf("failed to search: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalResultsCount, err := h.storage.Search.SearchCount(searchQuery, searchTypes, userId)
+	if err != nil {
+		log.Printf("failed to get search results count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalResultsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                   `json:"success"`
+		Results   []storage.SearchResult `json:"results"`
+		NoOfPages int                    `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Results: results, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
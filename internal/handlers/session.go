@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// sessions live in redis as a hash under session:{id}, each user has a set of their session ids
+// so that they can be listed and revoked together
+
+// a session lives as long as its refresh tokens, and is extended every time they rotate
+const SESSION_TTL = REFRESH_TOKEN_TTL
+
+var AuthSessionId = "AuthSessionId"
+
+// only updates a session that still exists, a plain HSET on an expired or revoked session would
+// bring its hash back without a ttl
+var touchSessionScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('HSET', KEYS[1], 'last_seen_at', ARGV[1])
+end
+return 0
+`)
+
+type Session struct {
+	Id         string `json:"id"`
+	UserId     int    `json:"user_id"`
+	Device     string `json:"device"`
+	IpAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	IsCurrent  bool   `json:"is_current"`
+}
+
+func sessionKey(sessionId string) string {
+	return fmt.Sprintf("session:%s", sessionId)
+}
+
+func userSessionsKey(userId int) string {
+	return fmt.Sprintf("user:%d:sessions", userId)
+}
+
+func (h *Handler) createSession(r *http.Request, userId int) (string, error) {
+
+	ctx := context.Background()
+	sessionId := generateToken(16)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ipAddress = r.RemoteAddr
+	}
+
+	pipe := h.rdb.TxPipeline()
+	pipe.HSet(ctx, sessionKey(sessionId), map[string]interface{}{
+		"user_id":      userId,
+		"device":       r.UserAgent(),
+		"ip_address":   ipAddress,
+		"created_at":   now,
+		"last_seen_at": now,
+	})
+	pipe.Expire(ctx, sessionKey(sessionId), SESSION_TTL)
+	pipe.SAdd(ctx, userSessionsKey(userId), sessionId)
+	pipe.Expire(ctx, userSessionsKey(userId), SESSION_TTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+
+	return sessionId, nil
+}
+
+// returns nil if the session does not exist (expired or revoked)
+func (h *Handler) getSession(sessionId string) (*Session, error) {
+
+	values, err := h.rdb.HGetAll(context.Background(), sessionKey(sessionId)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	userId, err := strconv.Atoi(values["user_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		Id:         sessionId,
+		UserId:     userId,
+		Device:     values["device"],
+		IpAddress:  values["ip_address"],
+		CreatedAt:  values["created_at"],
+		LastSeenAt: values["last_seen_at"],
+	}, nil
+}
+
+func (h *Handler) extendSession(userId int, sessionId string) error {
+
+	ctx := context.Background()
+
+	pipe := h.rdb.TxPipeline()
+	pipe.Expire(ctx, sessionKey(sessionId), SESSION_TTL)
+	pipe.Expire(ctx, userSessionsKey(userId), SESSION_TTL)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (h *Handler) touchSession(sessionId string) error {
+	return touchSessionScript.Run(context.Background(), h.rdb, []string{sessionKey(sessionId)}, time.Now().UTC().Format(time.RFC3339)).Err()
+}
+
+func (h *Handler) getUserSessions(userId int) ([]Session, error) {
+
+	ctx := context.Background()
+	var sessions []Session
+
+	sessionIds, err := h.rdb.SMembers(ctx, userSessionsKey(userId)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sessionId := range sessionIds {
+
+		session, err := h.getSession(sessionId)
+		if err != nil {
+			return nil, err
+		}
+
+		if session == nil {
+			// session expired, clean up the stale id
+			_ = h.rdb.SRem(ctx, userSessionsKey(userId), sessionId).Err()
+			continue
+		}
+
+		sessions = append(sessions, *session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt > sessions[j].LastSeenAt
+	})
+
+	return sessions, nil
+}
+
+func (h *Handler) revokeSession(userId int, sessionId string) error {
+
+	ctx := context.Background()
+
+	pipe := h.rdb.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionId))
+	pipe.SRem(ctx, userSessionsKey(userId), sessionId)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (h *Handler) revokeAllSessions(userId int) error {
+
+	ctx := context.Background()
+
+	sessionIds, err := h.rdb.SMembers(ctx, userSessionsKey(userId)).Result()
+	if err != nil {
+		return err
+	}
+
+	pipe := h.rdb.TxPipeline()
+	for _, sessionId := range sessionIds {
+		pipe.Del(ctx, sessionKey(sessionId))
+	}
+	pipe.Del(ctx, userSessionsKey(userId))
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (h *Handler) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentSessionId, ok := r.Context().Value(AuthSessionId).(string)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	sessions, err := h.getUserSessions(userId)
+	if err != nil {
+		log.Printf("failed to get user sessions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].IsCurrent = sessions[i].Id == currentSessionId
+	}
+
+	type Response struct {
+		Success  bool      `json:"success"`
+		Sessions []Session `json:"sessions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Sessions: sessions}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentSessionId, ok := r.Context().Value(AuthSessionId).(string)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, err := h.getSession(sessionId)
+	if err != nil {
+		log.Printf("failed to get session: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if session == nil || session.UserId != userId {
+		writeJSONError(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.revokeRefreshTokenFamily(userId, session.Id); err != nil {
+		log.Printf("failed to revoke session: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if session.Id == currentSessionId {
+		clearAuthCookies(w)
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "revoked session successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) RevokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.RefreshTokens.RevokeUserRefreshTokens(userId); err != nil {
+		log.Printf("failed to revoke refresh tokens: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.revokeAllSessions(userId); err != nil {
+		log.Printf("failed to revoke all sessions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	clearAuthCookies(w)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "revoked all sessions successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
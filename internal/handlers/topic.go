@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"math"
 	"net/http"
 	"strconv"
@@ -25,6 +26,12 @@ func (h *Handler) CreateTopicHandler(w http.ResponseWriter, r *http.Request) {
 
 	var createTopicPayload CreateTopicRequest
 
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	if err := readJSON(r, &createTopicPayload); err != nil {
 		writeJSONError(w, "invalid request body", http.StatusBadRequest)
 		return
@@ -49,8 +56,13 @@ func (h *Handler) CreateTopicHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create new topic
-	topic, err := h.storage.Topics.CreateTopic(topicName)
+	topic, err := h.storage.Topics.CreateTopic(topicName, &storage.AuditLogEntry{
+		ActorId:    userId,
+		Action:     storage.AuditActionTopicCreate,
+		TargetType: storage.AuditTargetTopic,
+	})
 	if err != nil {
+		log.Printf("failed to create topic: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -66,8 +78,15 @@ func (h *Handler) CreateTopicHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// admin route, ?reason= is recorded in the audit log
 func (h *Handler) DeleteTopicHandler(w http.ResponseWriter, r *http.Request) {
 
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	topicId, err := strconv.Atoi(chi.URLParam(r, "topicId"))
 	if err != nil {
 		writeJSONError(w, "invalid request param topicId", http.StatusBadRequest)
@@ -86,7 +105,15 @@ func (h *Handler) DeleteTopicHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//	delete topic
-	if err = h.storage.Topics.DeleteTopicById(topic.Id); err != nil {
+	if err = h.storage.Topics.DeleteTopicById(topic.Id, &storage.AuditLogEntry{
+		ActorId:    userId,
+		Action:     storage.AuditActionTopicDelete,
+		TargetType: storage.AuditTargetTopic,
+		TargetId:   topic.Id,
+		Before:     topic,
+		Reason:     strings.TrimSpace(r.URL.Query().Get("reason")),
+	}); err != nil {
+		log.Printf("failed to delete topic: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -101,8 +128,15 @@ func (h *Handler) DeleteTopicHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// admin route
 func (h *Handler) UpdateTopicHandler(w http.ResponseWriter, r *http.Request) {
 
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	topicId, err := strconv.Atoi(chi.URLParam(r, "topicId"))
 	if err != nil {
 		writeJSONError(w, "invalid request param topicId", http.StatusBadRequest)
@@ -159,8 +193,15 @@ func (h *Handler) UpdateTopicHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	updatedTopic, err := h.storage.Topics.UpdateTopicById(topic.Id, newTopicName)
+	updatedTopic, err := h.storage.Topics.UpdateTopicById(topic.Id, newTopicName, &storage.AuditLogEntry{
+		ActorId:    userId,
+		Action:     storage.AuditActionTopicUpdate,
+		TargetType: storage.AuditTargetTopic,
+		TargetId:   topic.Id,
+		Before:     topic,
+	})
 	if err != nil {
+		log.Printf("failed to update topic: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -2,17 +2,44 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/dhruv15803/go-community-platform/internal/storage"
+	"github.com/dhruv15803/go-community-platform/internal/utils"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UpdateUsernameRequest struct {
 	Username string `json:"username"`
 }
 
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// omitted fields are left unchanged, an empty string clears the field
+type UpdateProfileRequest struct {
+	UserImage   *string `json:"user_image"`
+	Bio         *string `json:"bio"`
+	Location    *string `json:"location"`
+	DateOfBirth *string `json:"date_of_birth"` // YYYY-MM-DD
+}
+
+const (
+	MAX_BIO_LENGTH      = 300
+	MAX_LOCATION_LENGTH = 100
+	MIN_USER_AGE        = 13
+	MAX_USER_AGE        = 120
+)
+
 func (h *Handler) UpdateUsernameHandler(w http.ResponseWriter, r *http.Request) {
 
 	// authenticated endpoint
@@ -92,3 +119,224 @@ func (h *Handler) UpdateUsernameHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 }
+
+func (h *Handler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var deleteAccountPayload DeleteAccountRequest
+
+	if err := readJSON(r, &deleteAccountPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	password := strings.TrimSpace(deleteAccountPayload.Password)
+
+	if password == "" {
+		writeJSONError(w, "password is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		writeJSONError(w, "invalid password", http.StatusBadRequest)
+		return
+	}
+
+	// $ACCOUNT_DELETION_POLICY decides whether a deleted user's content is kept (anonymize) or removed (cascade)
+	deletionPolicy := storage.UserDeletionPolicy(os.Getenv("ACCOUNT_DELETION_POLICY"))
+	if deletionPolicy != storage.UserDeletionPolicyCascade {
+		deletionPolicy = storage.UserDeletionPolicyAnonymize
+	}
+
+	if err := h.storage.Users.DeleteUserById(user.Id, deletionPolicy); err != nil {
+		if errors.Is(err, storage.ErrNoCommunitySuccessor) {
+			writeJSONError(w, "cannot delete account, no admin is available to take over your communities", http.StatusConflict)
+			return
+		} else {
+			log.Printf("failed to delete user %d: %v\n", user.Id, err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.revokeAllSessions(user.Id); err != nil {
+		log.Printf("failed to revoke sessions of deleted user %d: %v\n", user.Id, err)
+	}
+
+	clearAuthCookies(w)
+
+	// uploads that are still referenced (kept posts, transferred communities) are left in place
+	referencedUploadUrls, err := h.storage.Users.GetReferencedUserUploads(user.Id)
+	if err != nil {
+		log.Printf("failed to get referenced uploads of deleted user %d: %v\n", user.Id, err)
+	}
+
+	uploadsPrefix := fmt.Sprintf("uploads/userId-%d/", user.Id)
+	var keepKeys []string
+	for _, url := range referencedUploadUrls {
+		if i := strings.Index(url, uploadsPrefix); i != -1 {
+			keepKeys = append(keepKeys, url[i:])
+		}
+	}
+
+	// skip the cleanup if we could not tell which uploads are still in use
+	if err == nil {
+		s3CleanupJobJson, err := json.Marshal(S3CleanupJob{UserId: user.Id, Prefix: uploadsPrefix, KeepKeys: keepKeys})
+		if err == nil && !h.pushJob("queue:s3:cleanup", s3CleanupJobJson) {
+			log.Printf("failed to push s3 cleanup job for deleted user %d\n", user.Id)
+		}
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "account deleted successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.Users.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusNotFound)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var updateProfilePayload UpdateProfileRequest
+
+	if err := readJSON(r, &updateProfilePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// start from the current profile and apply only the fields that were sent
+	userImage := user.UserImage
+	bio := user.Bio
+	location := user.Location
+	dateOfBirth := user.DateOfBirth
+
+	if dateOfBirth != nil && len(*dateOfBirth) >= len("2006-01-02") {
+		// dates are scanned back as timestamps, keep only the date part
+		date := (*dateOfBirth)[:len("2006-01-02")]
+		dateOfBirth = &date
+	}
+
+	if updateProfilePayload.UserImage != nil {
+
+		newUserImage := strings.TrimSpace(*updateProfilePayload.UserImage)
+
+		if newUserImage == "" {
+			userImage = nil
+		} else {
+			// only images uploaded by this user through /api/file/upload can be linked
+			if !strings.HasPrefix(newUserImage, userUploadsUrlPrefix(user.Id)) {
+				writeJSONError(w, "user image must be uploaded through /api/file/upload", http.StatusBadRequest)
+				return
+			}
+			userImage = &newUserImage
+		}
+	}
+
+	if updateProfilePayload.Bio != nil {
+
+		newBio := strings.TrimSpace(*updateProfilePayload.Bio)
+
+		if utf8.RuneCountInString(newBio) > MAX_BIO_LENGTH {
+			writeJSONError(w, fmt.Sprintf("bio cannot be longer than %d characters", MAX_BIO_LENGTH), http.StatusBadRequest)
+			return
+		}
+
+		if newBio == "" {
+			bio = nil
+		} else {
+			bio = &newBio
+		}
+	}
+
+	if updateProfilePayload.Location != nil {
+
+		newLocation := strings.TrimSpace(*updateProfilePayload.Location)
+
+		if utf8.RuneCountInString(newLocation) > MAX_LOCATION_LENGTH {
+			writeJSONError(w, fmt.Sprintf("location cannot be longer than %d characters", MAX_LOCATION_LENGTH), http.StatusBadRequest)
+			return
+		}
+
+		if newLocation == "" {
+			location = nil
+		} else {
+			location = &newLocation
+		}
+	}
+
+	if updateProfilePayload.DateOfBirth != nil {
+
+		newDateOfBirth := strings.TrimSpace(*updateProfilePayload.DateOfBirth)
+
+		if newDateOfBirth == "" {
+			dateOfBirth = nil
+		} else {
+
+			parsedDateOfBirth, err := time.Parse("2006-01-02", newDateOfBirth)
+			if err != nil {
+				writeJSONError(w, "date of birth should be in YYYY-MM-DD format", http.StatusBadRequest)
+				return
+			}
+
+			age := utils.CalculateAge(parsedDateOfBirth, time.Now())
+			if age < MIN_USER_AGE || age > MAX_USER_AGE {
+				writeJSONError(w, fmt.Sprintf("age should be between %d and %d years", MIN_USER_AGE, MAX_USER_AGE), http.StatusBadRequest)
+				return
+			}
+
+			dateOfBirth = &newDateOfBirth
+		}
+	}
+
+	updatedUser, err := h.storage.Users.UpdateUserProfileById(user.Id, userImage, bio, location, dateOfBirth)
+	if err != nil {
+		log.Printf("failed to update user profile: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "updated profile successfully", User: *updatedUser}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
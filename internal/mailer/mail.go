@@ -10,6 +10,12 @@ import (
 	"gopkg.in/gomail.v2"
 )
 
+// mail job types pushed onto the email queue and handled by the worker
+const (
+	MailJobTypeVerification  = "verification"
+	MailJobTypePasswordReset = "password_reset"
+)
+
 type Mailer struct {
 	host     string
 	port     int
@@ -60,3 +66,35 @@ func (m *Mailer) SendVerificationMail(fromEmail string, toEmail string, subject
 	return d.DialAndSend(message)
 
 }
+
+func (m *Mailer) SendPasswordResetMail(fromEmail string, toEmail string, subject string, token string, emailTemplatePath string) error {
+
+	tmpl := template.Must(template.ParseFiles(emailTemplatePath))
+
+	type PasswordResetMailData struct {
+		Subject          string
+		Email            string
+		PasswordResetUrl string
+	}
+
+	var body bytes.Buffer
+
+	if err := tmpl.Execute(&body, PasswordResetMailData{
+		Subject:          subject,
+		Email:            toEmail,
+		PasswordResetUrl: fmt.Sprintf("%s/reset-password?token=%s", os.Getenv("CLIENT_URL"), token),
+	}); err != nil {
+		return err
+	}
+
+	message := gomail.NewMessage()
+
+	message.SetHeader("From", fromEmail)
+	message.SetHeader("To", toEmail)
+	message.SetHeader("Subject", subject)
+	message.SetBody("text/html", body.String())
+
+	d := gomail.NewDialer(m.host, m.port, m.username, m.password)
+
+	return d.DialAndSend(message)
+}
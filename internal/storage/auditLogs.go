@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditAction string
+
+const (
+	AuditActionTopicCreate     AuditAction = "topic.create"
+	AuditActionTopicUpdate     AuditAction = "topic.update"
+	AuditActionTopicDelete     AuditAction = "topic.delete"
+	AuditActionPostDelete      AuditAction = "post.delete"
+	AuditActionCommentDelete   AuditAction = "comment.delete"
+	AuditActionUserBan         AuditAction = "user.ban"
+	AuditActionUserUnban       AuditAction = "user.unban"
+	AuditActionModeratorAdd    AuditAction = "moderator.add"
+	AuditActionModeratorRemove AuditAction = "moderator.remove"
+	AuditActionReportResolve   AuditAction = "report.resolve"
+)
+
+const (
+	AuditTargetTopic   = "topic"
+	AuditTargetPost    = "post"
+	AuditTargetComment = "comment"
+	AuditTargetUser    = "user"
+	AuditTargetReport  = "report"
+)
+
+type AuditLog struct {
+	Id             int              `db:"id" json:"id"`
+	ActorId        int              `db:"actor_id" json:"actor_id"`
+	CommunityId    *int             `db:"community_id" json:"community_id"`
+	Action         AuditAction      `db:"action" json:"action"`
+	TargetType     string           `db:"target_type" json:"target_type"`
+	TargetId       int              `db:"target_id" json:"target_id"`
+	BeforeSnapshot *json.RawMessage `db:"before_snapshot" json:"before_snapshot"`
+	AfterSnapshot  *json.RawMessage `db:"after_snapshot" json:"after_snapshot"`
+	Reason         string           `db:"reason" json:"reason"`
+	CreatedAt      string           `db:"created_at" json:"created_at"`
+}
+
+// AuditLogEntry describes a privileged action for the repo method performing it, which writes the entry
+// in the same transaction. Before and After are marshalled to JSON, repo methods fill After (and Before
+// when the caller can't know it) themselves
+type AuditLogEntry struct {
+	ActorId     int
+	CommunityId *int
+	Action      AuditAction
+	TargetType  string
+	TargetId    int
+	Before      interface{}
+	After       interface{}
+	Reason      string
+}
+
+// all fields are optional, From and To bound created_at inclusively
+type AuditLogFilter struct {
+	CommunityId *int
+	ActorId     *int
+	Action      AuditAction
+	From        *time.Time
+	To          *time.Time
+}
+
+func auditSnapshot(v interface{}) (*string, error) {
+
+	if v == nil {
+		return nil, nil
+	}
+
+	snapshot, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotStr := string(snapshot)
+	return &snapshotStr, nil
+}
+
+// insertAuditLog writes the entry as part of the action's transaction, a nil entry is skipped
+func insertAuditLog(tx *sqlx.Tx, entry *AuditLogEntry) error {
+
+	if entry == nil {
+		return nil
+	}
+
+	before, err := auditSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := auditSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO audit_logs(actor_id, community_id, action, target_type, target_id, before_snapshot, after_snapshot, reason)
+	VALUES($1,$2,$3,$4,$5,$6::jsonb,$7::jsonb,$8)`
+
+	_, err = tx.Exec(query, entry.ActorId, entry.CommunityId, entry.Action, entry.TargetType, entry.TargetId, before, after, entry.Reason)
+	return err
+}
+
+type AuditLogRepo struct {
+	db *sqlx.DB
+}
+
+func NewAuditLogRepo(db *sqlx.DB) *AuditLogRepo {
+	return &AuditLogRepo{
+		db: db,
+	}
+}
+
+func auditLogWhereClause(filter AuditLogFilter) (string, []interface{}) {
+
+	var conditions []string
+	var args []interface{}
+
+	if filter.CommunityId != nil {
+		args = append(args, *filter.CommunityId)
+		conditions = append(conditions, fmt.Sprintf("community_id=$%d", len(args)))
+	}
+
+	if filter.ActorId != nil {
+		args = append(args, *filter.ActorId)
+		conditions = append(conditions, fmt.Sprintf("actor_id=$%d", len(args)))
+	}
+
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action=$%d", len(args)))
+	}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetAuditLogs gets audit log entries matching the filter, most recent first
+func (a *AuditLogRepo) GetAuditLogs(filter AuditLogFilter, offset int, limit int) ([]AuditLog, error) {
+
+	var auditLogs []AuditLog
+
+	whereClause, args := auditLogWhereClause(filter)
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`SELECT id, actor_id, community_id, action, target_type, target_id, before_snapshot, after_snapshot, reason, created_at
+	FROM audit_logs %s
+	ORDER BY created_at DESC, id DESC
+	LIMIT $%d OFFSET $%d`, whereClause, len(args)-1, len(args))
+
+	rows, err := a.db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var auditLog AuditLog
+
+		if err := rows.StructScan(&auditLog); err != nil {
+			return nil, err
+		}
+
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	return auditLogs, nil
+}
+
+func (a *AuditLogRepo) GetAuditLogsCount(filter AuditLogFilter) (int, error) {
+
+	var totalCount int
+
+	whereClause, args := auditLogWhereClause(filter)
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM audit_logs %s`, whereClause)
+
+	if err := a.db.QueryRow(query, args...).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
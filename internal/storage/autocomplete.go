@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AutocompleteType string
+
+const (
+	AutocompleteTypeCommunity AutocompleteType = "community"
+	AutocompleteTypeTopic     AutocompleteType = "topic"
+	AutocompleteTypeUser      AutocompleteType = "user"
+)
+
+var AutocompleteTypes = []AutocompleteType{AutocompleteTypeCommunity, AutocompleteTypeTopic, AutocompleteTypeUser}
+
+// AutocompleteSuggestion is a community, topic or user whose name starts with the typed prefix. Popularity is
+// the member count of communities, the number of communities and users following a topic and the post count of users
+type AutocompleteSuggestion struct {
+	SuggestionType AutocompleteType `db:"suggestion_type" json:"suggestion_type"`
+	Id             int              `db:"id" json:"id"`
+	Name           string           `db:"name" json:"name"`
+	Image          *string          `db:"image" json:"image"` // nil for topics
+	Popularity     int              `db:"popularity" json:"popularity"`
+}
+
+type AutocompleteRepo struct {
+	db *sqlx.DB
+}
+
+func NewAutocompleteRepo(db *sqlx.DB) *AutocompleteRepo {
+	return &AutocompleteRepo{
+		db: db,
+	}
+}
+
+// escapes the LIKE wildcards in a prefix typed by a user
+var likePatternEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Autocomplete gets the limit most popular communities, topics and users of the given types whose names start
+// with prefix, ignoring case. exact matches come first. private communities are left out
+func (a *AutocompleteRepo) Autocomplete(prefix string, suggestionTypes []AutocompleteType, limit int) ([]AutocompleteSuggestion, error) {
+
+	var suggestions []AutocompleteSuggestion
+
+	var parts []string
+
+	for _, suggestionType := range suggestionTypes {
+		switch suggestionType {
+		case AutocompleteTypeCommunity:
+			parts = append(parts, `(SELECT 'community' AS suggestion_type, c.id, c.community_name AS name, c.community_image AS image,
+			(SELECT COUNT(*) FROM user_communities AS uc WHERE uc.community_id = c.id) AS popularity
+			FROM communities AS c
+			WHERE LOWER(c.community_name) LIKE $1 AND c.community_visibility <> 'private'
+			ORDER BY LOWER(c.community_name) = $2 DESC, popularity DESC, LENGTH(c.community_name), c.community_name
+			LIMIT $3)`)
+		case AutocompleteTypeTopic:
+			parts = append(parts, `(SELECT 'topic' AS suggestion_type, t.id, t.topic_name AS name, NULL::text AS image,
+			(SELECT COUNT(*) FROM community_topics AS ct WHERE ct.topic_id = t.id) +
+			(SELECT COUNT(*) FROM user_topic_preferences AS utp WHERE utp.topic_id = t.id) AS popularity
+			FROM topics AS t
+			WHERE LOWER(t.topic_name) LIKE $1
+			ORDER BY LOWER(t.topic_name) = $2 DESC, popularity DESC, LENGTH(t.topic_name), t.topic_name
+			LIMIT $3)`)
+		case AutocompleteTypeUser:
+			parts = append(parts, `(SELECT 'user' AS suggestion_type, u.id, u.username AS name, u.user_image AS image,
+			(SELECT COUNT(*) FROM posts AS p WHERE p.post_owner_id = u.id) AS popularity
+			FROM users AS u
+			WHERE LOWER(u.username) LIKE $1 AND u.is_verified = true
+			ORDER BY LOWER(u.username) = $2 DESC, popularity DESC, LENGTH(u.username), u.username
+			LIMIT $3)`)
+		}
+	}
+
+	// every type keeps its own top matches, the merged list is cut down to limit again
+	query := fmt.Sprintf(`SELECT suggestion_type, id, name, image, popularity FROM (%s) AS suggestions
+	ORDER BY LOWER(name) = $2 DESC, popularity DESC, LENGTH(name), name
+	LIMIT $3`, strings.Join(parts, "\nUNION ALL\n"))
+
+	lowerPrefix := strings.ToLower(prefix)
+
+	if err := a.db.Select(&suggestions, query, likePatternEscaper.Replace(lowerPrefix)+"%", lowerPrefix, limit); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
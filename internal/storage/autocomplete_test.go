@@ -0,0 +1,29 @@
+package storage
+
+import "testing"
+
+func TestLikePatternEscaper(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{name: "plain", prefix: "golang", want: "golang"},
+		{name: "percent", prefix: "100%", want: `100\%`},
+		{name: "underscore", prefix: "go_lang", want: `go\_lang`},
+		{name: "backslash", prefix: `go\lang`, want: `go\\lang`},
+		{name: "escaped wildcard", prefix: `\%`, want: `\\\%`},
+		{name: "every wildcard", prefix: `%_\`, want: `\%\_\\`},
+		{name: "empty", prefix: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if got := likePatternEscaper.Replace(tt.prefix); got != tt.want {
+				t.Errorf("likePatternEscaper.Replace(%q) = %q, want %q", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import "github.com/jmoiron/sqlx"
+
+// a bookmark collection is a named folder a user can file their bookmarks into
+type BookmarkCollection struct {
+	Id                  int     `db:"id" json:"id"`
+	CollectionName      string  `db:"collection_name" json:"collection_name"`
+	CollectionOwnerId   int     `db:"collection_owner_id" json:"collection_owner_id"`
+	CollectionCreatedAt string  `db:"collection_created_at" json:"collection_created_at"`
+	CollectionUpdatedAt *string `db:"collection_updated_at" json:"collection_updated_at"`
+}
+
+type BookmarkCollectionWithMetaData struct {
+	BookmarkCollection
+	BookmarksCount int `db:"bookmarks_count" json:"bookmarks_count"`
+}
+
+type BookmarkCollectionRepo struct {
+	db *sqlx.DB
+}
+
+func NewBookmarkCollectionRepo(db *sqlx.DB) *BookmarkCollectionRepo {
+	return &BookmarkCollectionRepo{
+		db: db,
+	}
+}
+
+func (b *BookmarkCollectionRepo) CreateBookmarkCollection(collectionName string, collectionOwnerId int) (*BookmarkCollection, error) {
+
+	var bookmarkCollection BookmarkCollection
+
+	query := `INSERT INTO bookmark_collections(collection_name,collection_owner_id) VALUES($1,$2) RETURNING 
+	id,collection_name,collection_owner_id,collection_created_at,collection_updated_at`
+
+	if err := b.db.QueryRowx(query, collectionName, collectionOwnerId).StructScan(&bookmarkCollection); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkCollection, nil
+}
+
+func (b *BookmarkCollectionRepo) GetBookmarkCollectionById(id int) (*BookmarkCollection, error) {
+
+	var bookmarkCollection BookmarkCollection
+
+	query := `SELECT id, collection_name, collection_owner_id, collection_created_at, collection_updated_at 
+	FROM bookmark_collections WHERE id=$1`
+
+	if err := b.db.QueryRowx(query, id).StructScan(&bookmarkCollection); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkCollection, nil
+}
+
+func (b *BookmarkCollectionRepo) GetBookmarkCollectionByName(collectionName string, collectionOwnerId int) (*BookmarkCollection, error) {
+
+	var bookmarkCollection BookmarkCollection
+
+	query := `SELECT id, collection_name, collection_owner_id, collection_created_at, collection_updated_at 
+	FROM bookmark_collections WHERE collection_name=$1 AND collection_owner_id=$2`
+
+	if err := b.db.QueryRowx(query, collectionName, collectionOwnerId).StructScan(&bookmarkCollection); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkCollection, nil
+}
+
+func (b *BookmarkCollectionRepo) GetUserBookmarkCollections(userId int) ([]BookmarkCollectionWithMetaData, error) {
+
+	var bookmarkCollections []BookmarkCollectionWithMetaData
+
+	query := `SELECT bc.id, collection_name, collection_owner_id, collection_created_at, collection_updated_at,
+	COUNT(pb.bookmarked_post_id) AS bookmarks_count
+	FROM bookmark_collections AS bc LEFT JOIN post_bookmarks AS pb ON bc.id = pb.collection_id
+	WHERE collection_owner_id=$1
+	GROUP BY bc.id
+	ORDER BY collection_name ASC`
+
+	rows, err := b.db.Queryx(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var bookmarkCollection BookmarkCollectionWithMetaData
+
+		if err := rows.StructScan(&bookmarkCollection); err != nil {
+			return nil, err
+		}
+
+		bookmarkCollections = append(bookmarkCollections, bookmarkCollection)
+	}
+
+	return bookmarkCollections, nil
+}
+
+func (b *BookmarkCollectionRepo) UpdateBookmarkCollectionName(id int, collectionName string) (*BookmarkCollection, error) {
+
+	var bookmarkCollection BookmarkCollection
+
+	query := `UPDATE bookmark_collections SET collection_name=$1, collection_updated_at=NOW() WHERE id=$2 RETURNING 
+	id,collection_name,collection_owner_id,collection_created_at,collection_updated_at`
+
+	if err := b.db.QueryRowx(query, collectionName, id).StructScan(&bookmarkCollection); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkCollection, nil
+}
+
+// DeleteBookmarkCollectionById deletes the collection, bookmarks filed in it are kept as unfiled bookmarks
+func (b *BookmarkCollectionRepo) DeleteBookmarkCollectionById(id int) error {
+
+	query := `DELETE FROM bookmark_collections WHERE id=$1`
+
+	_, err := b.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePostBookmarkCollection files a user's bookmark into a collection, a nil collection id unfiles it.
+// Returns sql.ErrNoRows if the user has not bookmarked the post
+func (b *BookmarkCollectionRepo) UpdatePostBookmarkCollection(userId int, postId int, collectionId *int) (*PostBookmark, error) {
+
+	var postBookmark PostBookmark
+
+	query := `UPDATE post_bookmarks SET collection_id=$1 WHERE bookmarked_by_id=$2 AND bookmarked_post_id=$3 RETURNING 
+	bookmarked_by_id,bookmarked_post_id,bookmarked_at,collection_id`
+
+	if err := b.db.QueryRowx(query, collectionId, userId, postId).StructScan(&postBookmark); err != nil {
+		return nil, err
+	}
+
+	return &postBookmark, nil
+}
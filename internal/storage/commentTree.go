@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// CommentTreeNode is a comment along with the replies loaded under it. RepliesCursor is set when
+// the comment has more replies than were loaded, because of the children, depth or nodes limit
+type CommentTreeNode struct {
+	PostCommentWithMetaData
+	RepliesCount  int               `json:"replies_count"`
+	Replies       []CommentTreeNode `json:"replies"`
+	RepliesCursor *string           `json:"replies_cursor"`
+}
+
+// CommentCursor continues a level of the comment tree, the children of ParentCommentId (top level
+// comments when nil) after the comment with CreatedAt and Id. Id is 0 when starting from the first child
+type CommentCursor struct {
+	ParentCommentId *int   `json:"parent_comment_id"`
+	CreatedAt       string `json:"created_at,omitempty"`
+	Id              int    `json:"id,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (c CommentCursor) Encode() string {
+
+	cursorJSON, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+func DecodeCommentCursor(cursor string) (*CommentCursor, error) {
+
+	var commentCursor CommentCursor
+
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(cursorJSON, &commentCursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if commentCursor.Id != 0 && commentCursor.CreatedAt == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &commentCursor, nil
+}
+
+// GetCommentTree gets a level of the post's comment tree along with the replies under it, newest first.
+// every comment loads at most maxChildren replies and comments maxDepth levels down load none, those carry
+// a cursor to continue from instead. the tree stops growing at maxNodes comments, the comments whose replies
+// got cut off by it carry a cursor as well. the returned cursor continues the level itself and is nil at its end
+func (c *PostCommentRepo) GetCommentTree(postId int, cursor *CommentCursor, maxDepth int, maxChildren int, maxNodes int) ([]CommentTreeNode, *string, error) {
+
+	if cursor == nil {
+		cursor = &CommentCursor{}
+	}
+
+	// the level itself is never cut off by the nodes limit, its cursor couldn't continue it otherwise
+	if maxNodes < maxChildren {
+		maxNodes = maxChildren
+	}
+
+	args := []interface{}{postId, maxChildren, maxDepth, maxNodes}
+
+	levelClause := `post_id=$1 AND parent_comment_id IS NULL`
+	if cursor.ParentCommentId != nil {
+		args = append(args, *cursor.ParentCommentId)
+		levelClause = fmt.Sprintf(`post_id=$1 AND parent_comment_id=$%d`, len(args))
+	}
+
+	if cursor.Id != 0 {
+		args = append(args, cursor.CreatedAt, cursor.Id)
+		levelClause += fmt.Sprintf(` AND (comment_created_at, id) < ($%d::timestamp, $%d)`, len(args)-1, len(args))
+	}
+
+	// the lateral join keeps the children limit per comment rather than per level. the recursion
+	// goes a level at a time and the outer LIMIT stops it once it has maxNodes comments, so only
+	// the replies of the deepest comments loaded get cut off
+	query := fmt.Sprintf(`
+	WITH RECURSIVE comment_tree AS (
+	    (SELECT id, 1 AS depth FROM post_comments
+	    WHERE %s
+	    ORDER BY comment_created_at DESC, id DESC
+	    LIMIT $2)
+	  UNION ALL
+	    SELECT child.id, ct.depth + 1
+	    FROM comment_tree AS ct CROSS JOIN LATERAL (
+	        SELECT id FROM post_comments
+	        WHERE parent_comment_id = ct.id
+	        ORDER BY comment_created_at DESC, id DESC
+	        LIMIT $2
+	    ) AS child
+	    WHERE ct.depth < $3
+	), capped_tree AS (
+	    SELECT id, depth FROM comment_tree LIMIT $4
+	)
+	SELECT
+	  	pc.id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at, comment_deleted_at,
+	  	u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth,
+       	verified_at, created_at, updated_at,
+	  	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) AS comment_upvotes_count,
+	  	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_downvotes_count,
+	  	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) - COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_score,
+	  	COALESCE(COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(cv.voter_id)), 0), 0) AS comment_upvote_ratio,
+	  	(SELECT COUNT(*) FROM post_comments AS r WHERE r.parent_comment_id = pc.id) AS replies_count
+	FROM
+	  capped_tree AS ct
+	  INNER JOIN post_comments AS pc ON ct.id = pc.id
+	  INNER JOIN users AS u ON pc.comment_owner_id = u.id
+	  LEFT JOIN post_comment_votes AS cv ON pc.id = cv.comment_id
+	GROUP BY
+	  pc.id, u.id, ct.depth
+	ORDER BY
+	  ct.depth, comment_created_at DESC, pc.id DESC`, levelClause)
+
+	rows, err := c.db.Queryx(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// rows come level by level, so every comment's parent is read before the comment
+	nodes := make(map[int]*CommentTreeNode)
+	childIds := make(map[int][]int)
+	var levelIds []int
+
+	for rows.Next() {
+
+		var node CommentTreeNode
+
+		if err := rows.Scan(append(postCommentWithMetaDataScanDest(&node.PostCommentWithMetaData), &node.RepliesCount)...); err != nil {
+			return nil, nil, err
+		}
+
+		redactDeletedComment(&node.PostCommentWithMetaData)
+
+		nodes[node.Id] = &node
+
+		if node.ParentCommentId != nil && nodes[*node.ParentCommentId] != nil {
+			childIds[*node.ParentCommentId] = append(childIds[*node.ParentCommentId], node.Id)
+		} else {
+			levelIds = append(levelIds, node.Id)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	level := buildCommentTreeLevel(levelIds, nodes, childIds)
+
+	if len(level) < maxChildren {
+		return level, nil, nil
+	}
+
+	last := level[len(level)-1]
+	nextCursor := CommentCursor{ParentCommentId: cursor.ParentCommentId, CreatedAt: last.CommentCreatedAt, Id: last.Id}
+
+	hasMoreQuery := `SELECT id FROM post_comments WHERE post_id=$1 AND parent_comment_id IS NOT DISTINCT FROM $2
+	AND (comment_created_at, id) < ($3::timestamp, $4) LIMIT 1`
+
+	var nextId int
+	if err := c.db.QueryRow(hasMoreQuery, postId, cursor.ParentCommentId, last.CommentCreatedAt, last.Id).Scan(&nextId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return level, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	encodedCursor := nextCursor.Encode()
+	return level, &encodedCursor, nil
+}
+
+func buildCommentTreeLevel(ids []int, nodes map[int]*CommentTreeNode, childIds map[int][]int) []CommentTreeNode {
+
+	level := make([]CommentTreeNode, 0, len(ids))
+
+	for _, id := range ids {
+
+		node := *nodes[id]
+		node.Replies = buildCommentTreeLevel(childIds[id], nodes, childIds)
+
+		if node.RepliesCount > len(node.Replies) {
+
+			parentCommentId := node.Id
+			repliesCursor := CommentCursor{ParentCommentId: &parentCommentId}
+
+			if len(node.Replies) > 0 {
+				lastReply := node.Replies[len(node.Replies)-1]
+				repliesCursor.CreatedAt = lastReply.CommentCreatedAt
+				repliesCursor.Id = lastReply.Id
+			}
+
+			encodedCursor := repliesCursor.Encode()
+			node.RepliesCursor = &encodedCursor
+		}
+
+		level = append(level, node)
+	}
+
+	return level
+}
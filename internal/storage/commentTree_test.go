@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCommentCursorRoundTrip(t *testing.T) {
+
+	parentCommentId := 7
+
+	tests := []struct {
+		name   string
+		cursor CommentCursor
+	}{
+		{name: "top level from the start", cursor: CommentCursor{}},
+		{name: "top level after a comment", cursor: CommentCursor{CreatedAt: "2025-01-02T03:04:05Z", Id: 42}},
+		{name: "replies from the start", cursor: CommentCursor{ParentCommentId: &parentCommentId}},
+		{name: "replies after a comment", cursor: CommentCursor{ParentCommentId: &parentCommentId, CreatedAt: "2025-01-02T03:04:05Z", Id: 42}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			decoded, err := DecodeCommentCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("DecodeCommentCursor() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(*decoded, tt.cursor) {
+				t.Errorf("DecodeCommentCursor() = %+v, want %+v", *decoded, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCommentCursorInvalid(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "not json", cursor: base64.RawURLEncoding.EncodeToString([]byte("cursor"))},
+		{name: "id without created at", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"id":42}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if _, err := DecodeCommentCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCommentCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestBuildCommentTreeLevelRepliesCursor(t *testing.T) {
+
+	newNode := func(id int, createdAt string, repliesCount int) *CommentTreeNode {
+		var node CommentTreeNode
+		node.Id = id
+		node.CommentCreatedAt = createdAt
+		node.RepliesCount = repliesCount
+		return &node
+	}
+
+	// 1 has all of its replies loaded, 2 got cut off after its first reply and 3 before any of them,
+	// by the depth or the nodes limit
+	nodes := map[int]*CommentTreeNode{
+		1: newNode(1, "2025-01-01T00:00:03Z", 1),
+		2: newNode(2, "2025-01-01T00:00:02Z", 3),
+		3: newNode(3, "2025-01-01T00:00:01Z", 2),
+		4: newNode(4, "2025-01-01T00:00:06Z", 0),
+		5: newNode(5, "2025-01-01T00:00:05Z", 0),
+	}
+	childIds := map[int][]int{1: {4}, 2: {5}}
+
+	level := buildCommentTreeLevel([]int{1, 2, 3}, nodes, childIds)
+
+	parentCommentId := func(id int) *int { return &id }
+
+	want := map[int]*CommentCursor{
+		1: nil,
+		2: {ParentCommentId: parentCommentId(2), CreatedAt: "2025-01-01T00:00:05Z", Id: 5},
+		3: {ParentCommentId: parentCommentId(3)},
+	}
+
+	for _, node := range level {
+
+		if node.RepliesCursor == nil {
+			if want[node.Id] != nil {
+				t.Errorf("comment %d replies cursor = nil, want %+v", node.Id, *want[node.Id])
+			}
+			continue
+		}
+
+		got, err := DecodeCommentCursor(*node.RepliesCursor)
+		if err != nil {
+			t.Fatalf("DecodeCommentCursor() error = %v", err)
+		}
+
+		if want[node.Id] == nil || !reflect.DeepEqual(*got, *want[node.Id]) {
+			t.Errorf("comment %d replies cursor = %+v, want %+v", node.Id, *got, want[node.Id])
+		}
+	}
+}
@@ -1,28 +1,81 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/jmoiron/sqlx"
 )
 
 type Community struct {
-	Id                   int     `db:"id" json:"id"`
-	CommunityName        string  `db:"community_name" json:"community_name"`
-	CommunityDescription *string `db:"community_description" json:"community_description"`
-	CommunityImage       *string `db:"community_image" json:"community_image"`
-	CommunityOwnerId     int     `db:"community_owner_id" json:"community_owner_id"`
-	CommunityCreatedAt   string  `db:"community_created_at" json:"community_created_at"`
-	CommunityUpdatedAt   *string `db:"community_updated_at" json:"community_updated_at"`
+	Id                   int                 `db:"id" json:"id"`
+	CommunityName        string              `db:"community_name" json:"community_name"`
+	CommunityDescription *string             `db:"community_description" json:"community_description"`
+	CommunityImage       *string             `db:"community_image" json:"community_image"`
+	CommunityOwnerId     int                 `db:"community_owner_id" json:"community_owner_id"`
+	CommunityCreatedAt   string              `db:"community_created_at" json:"community_created_at"`
+	CommunityUpdatedAt   *string             `db:"community_updated_at" json:"community_updated_at"`
+	CommunityVisibility  CommunityVisibility `db:"community_visibility" json:"community_visibility"`
 }
 
+// who can read and post in a community
+type CommunityVisibility string
+
+const (
+	CommunityVisibilityPublic     CommunityVisibility = "public"     // anyone reads, anyone can join
+	CommunityVisibilityRestricted CommunityVisibility = "restricted" // anyone reads, joining needs approval
+	CommunityVisibilityPrivate    CommunityVisibility = "private"    // only members read, joining needs approval
+)
+
 type CommunityTopic struct {
 	CommunityId int `db:"community_id" json:"community_id"`
 	TopicId     int `db:"topic_id" json:"topic_id"`
 }
 
+// a user's role in a community, the owner is the community's community_owner_id,
+// members and moderators are stored on their user_communities row
+type CommunityRole string
+
+const (
+	CommunityRoleOwner     CommunityRole = "owner"
+	CommunityRoleModerator CommunityRole = "moderator"
+	CommunityRoleMember    CommunityRole = "member"
+)
+
 type UserCommunity struct {
+	UserId      int           `db:"user_id" json:"user_id"`
+	CommunityId int           `db:"community_id" json:"community_id"`
+	JoinedAt    string        `db:"joined_at" json:"joined_at"`
+	Role        CommunityRole `db:"role" json:"role"`
+}
+
+type CommunityOwnershipTransfer struct {
+	CommunityId int    `db:"community_id" json:"community_id"`
+	FromUserId  int    `db:"from_user_id" json:"from_user_id"`
+	ToUserId    int    `db:"to_user_id" json:"to_user_id"`
+	Expiration  string `db:"expiration" json:"expiration"`
+	CreatedAt   string `db:"created_at" json:"created_at"`
+}
+
+type CommunityJoinRequest struct {
 	UserId      int    `db:"user_id" json:"user_id"`
 	CommunityId int    `db:"community_id" json:"community_id"`
-	JoinedAt    string `db:"joined_at" json:"joined_at"`
+	RequestedAt string `db:"requested_at" json:"requested_at"`
+}
+
+type CommunityJoinRequestWithUser struct {
+	CommunityJoinRequest
+	User User `json:"user"`
+}
+
+type CommunityInvite struct {
+	Id          int     `db:"id" json:"id"`
+	InviteCode  string  `db:"invite_code" json:"invite_code"`
+	CommunityId int     `db:"community_id" json:"community_id"`
+	CreatedById int     `db:"created_by_id" json:"created_by_id"`
+	MaxUses     *int    `db:"max_uses" json:"max_uses"`
+	Uses        int     `db:"uses" json:"uses"`
+	Expiration  *string `db:"expiration" json:"expiration"`
+	CreatedAt   string  `db:"created_at" json:"created_at"`
 }
 
 type CommunityWithTopics struct {
@@ -48,7 +101,7 @@ func (c *CommunityRepo) GetCommunityByName(communityName string) (*Community, er
 
 	var community Community
 
-	query := `SELECT id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at 
+	query := `SELECT id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility 
 	FROM communities WHERE community_name=$1`
 
 	if err := c.db.QueryRowx(query, communityName).StructScan(&community); err != nil {
@@ -58,7 +111,7 @@ func (c *CommunityRepo) GetCommunityByName(communityName string) (*Community, er
 	return &community, nil
 }
 
-func (c *CommunityRepo) CreateCommunityWithTopics(communityName string, communityDescription string, communityImage string, communityOwnerId int, communityTopicIds []int) (*CommunityWithTopics, error) {
+func (c *CommunityRepo) CreateCommunityWithTopics(communityName string, communityDescription string, communityImage string, communityOwnerId int, communityVisibility CommunityVisibility, communityTopicIds []int) (*CommunityWithTopics, error) {
 
 	var communityWithTopics CommunityWithTopics
 	var community Community
@@ -74,10 +127,10 @@ func (c *CommunityRepo) CreateCommunityWithTopics(communityName string, communit
 		tx.Rollback()
 	}()
 
-	createCommunityQuery := `INSERT INTO communities(community_name,community_description,community_image,community_owner_id) VALUES($1,$2,$3,$4) RETURNING
-	id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at`
+	createCommunityQuery := `INSERT INTO communities(community_name,community_description,community_image,community_owner_id,community_visibility) VALUES($1,$2,$3,$4,$5) RETURNING
+	id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility`
 
-	if err := tx.QueryRowx(createCommunityQuery, communityName, communityDescription, communityImage, communityOwnerId).StructScan(&community); err != nil {
+	if err := tx.QueryRowx(createCommunityQuery, communityName, communityDescription, communityImage, communityOwnerId, communityVisibility).StructScan(&community); err != nil {
 		rollBackErr = err
 		return nil, rollBackErr
 	}
@@ -119,7 +172,7 @@ func (c *CommunityRepo) GetCommunityById(communityId int) (*Community, error) {
 
 	var community Community
 
-	query := `SELECT id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at 
+	query := `SELECT id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility 
 	FROM communities WHERE id=$1`
 
 	if err := c.db.QueryRowx(query, communityId).StructScan(&community); err != nil {
@@ -133,7 +186,7 @@ func (c *CommunityRepo) JoinCommunity(userId int, communityId int) (*UserCommuni
 
 	var userCommunity UserCommunity
 
-	query := `INSERT INTO user_communities(user_id,community_id) VALUES($1,$2) RETURNING user_id,community_id,joined_at`
+	query := `INSERT INTO user_communities(user_id,community_id) VALUES($1,$2) RETURNING user_id,community_id,joined_at,role`
 
 	if err := c.db.QueryRowx(query, userId, communityId).StructScan(&userCommunity); err != nil {
 		return nil, err
@@ -160,7 +213,7 @@ func (c *CommunityRepo) CheckCommunityForUser(userId int, communityId int) (bool
 
 	var userCommunity UserCommunity
 
-	query := `SELECT * FROM user_communities WHERE user_id=$1 AND community_id=$2`
+	query := `SELECT user_id, community_id, joined_at, role FROM user_communities WHERE user_id=$1 AND community_id=$2`
 
 	if err := c.db.QueryRowx(query, userId, communityId).StructScan(&userCommunity); err != nil {
 		return false, err
@@ -221,12 +274,12 @@ func (c *CommunityRepo) GetCommunityProfile(communityId int) (*CommunityWithMeta
 	var communityTopics []Topic
 	var totalCommunityMembersCount int
 
-	query := `SELECT c.id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at,
+	query := `SELECT c.id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility,
        u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at
 	FROM communities AS c INNER JOIN users AS u ON c.community_owner_id=u.id WHERE c.id=$1`
 
 	if err := c.db.QueryRowx(query, communityId).Scan(&community.Id, &community.CommunityName, &community.CommunityDescription,
-		&community.CommunityImage, &community.CommunityOwnerId, &community.CommunityCreatedAt, &community.CommunityUpdatedAt,
+		&community.CommunityImage, &community.CommunityOwnerId, &community.CommunityCreatedAt, &community.CommunityUpdatedAt, &community.CommunityVisibility,
 		&community.CommunityOwner.Id, &community.CommunityOwner.Email, &community.CommunityOwner.Password, &community.CommunityOwner.Username,
 		&community.CommunityOwner.IsVerified, &community.CommunityOwner.Role, &community.CommunityOwner.UserImage, &community.CommunityOwner.Bio,
 		&community.CommunityOwner.Location, &community.CommunityOwner.DateOfBirth, &community.CommunityOwner.VerifiedAt, &community.CommunityOwner.CreatedAt,
@@ -272,8 +325,8 @@ func (c *CommunityRepo) GetRecommendedCommunitiesForUser(userId int, offset int,
 
 	var communities []CommunityWithMetaData
 
-	query := `SELECT c.id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at,
-       u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at,
+	query := `SELECT c.id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility,
+       u.id, email, password, username, is_verified, u.role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at,
        COUNT(uc.user_id) AS members_count
 	FROM communities AS c INNER JOIN users AS u ON c.community_owner_id=u.id   
     LEFT JOIN user_communities AS uc ON c.id = uc.community_id
@@ -297,7 +350,7 @@ func (c *CommunityRepo) GetRecommendedCommunitiesForUser(userId int, offset int,
 
 		if err := rows.Scan(&community.Id, &community.CommunityName, &community.CommunityDescription,
 			&community.CommunityImage, &community.CommunityOwnerId, &community.CommunityCreatedAt,
-			&community.CommunityUpdatedAt, &community.CommunityOwner.Id, &community.CommunityOwner.Email,
+			&community.CommunityUpdatedAt, &community.CommunityVisibility, &community.CommunityOwner.Id, &community.CommunityOwner.Email,
 			&community.CommunityOwner.Password, &community.CommunityOwner.Username, &community.CommunityOwner.IsVerified,
 			&community.CommunityOwner.Role, &community.CommunityOwner.UserImage, &community.CommunityOwner.Bio,
 			&community.CommunityOwner.Location, &community.CommunityOwner.DateOfBirth, &community.CommunityOwner.VerifiedAt,
@@ -352,3 +405,584 @@ func (c *CommunityRepo) GetRecommendCommunitiesForUserCount(userId int) (int, er
 
 	return totalRecommendedCommunitiesCount, nil
 }
+
+// GetUserCommunities gets public and restricted communities the user owns or has joined, most recently joined first
+func (c *CommunityRepo) GetUserCommunities(userId int, offset int, limit int) ([]Community, error) {
+
+	var communities []Community
+
+	query := `SELECT c.id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility
+	FROM communities AS c LEFT JOIN user_communities AS uc ON c.id = uc.community_id AND uc.user_id=$1
+	WHERE (c.community_owner_id=$1 OR uc.user_id IS NOT NULL) AND c.community_visibility <> 'private'
+	ORDER BY COALESCE(uc.joined_at, c.community_created_at) DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := c.db.Queryx(query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var community Community
+
+		if err := rows.StructScan(&community); err != nil {
+			return nil, err
+		}
+
+		communities = append(communities, community)
+	}
+
+	return communities, nil
+}
+
+func (c *CommunityRepo) GetUserCommunitiesCount(userId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(*) FROM communities AS c 
+	WHERE (c.community_owner_id=$1 OR c.id IN (SELECT community_id FROM user_communities WHERE user_id=$1))
+	AND c.community_visibility <> 'private'`
+
+	if err := c.db.QueryRowx(query, userId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+// GetCommunityRole gets the role of a user in a community, an empty role means the user is not part of it
+func (c *CommunityRepo) GetCommunityRole(userId int, communityId int) (CommunityRole, error) {
+
+	var role *string
+
+	query := `SELECT CASE WHEN c.community_owner_id=$1 THEN 'owner' ELSE uc.role::TEXT END
+	FROM communities AS c LEFT JOIN user_communities AS uc ON c.id = uc.community_id AND uc.user_id=$1
+	WHERE c.id=$2`
+
+	if err := c.db.QueryRow(query, userId, communityId).Scan(&role); err != nil {
+		return "", err
+	}
+
+	if role == nil {
+		return "", nil
+	}
+
+	return CommunityRole(*role), nil
+}
+
+// UpdateCommunityMemberRole sets the role of a member, returns sql.ErrNoRows if the user is not a member
+func (c *CommunityRepo) UpdateCommunityMemberRole(userId int, communityId int, role CommunityRole, audit *AuditLogEntry) (*UserCommunity, error) {
+
+	var previousUserCommunity UserCommunity
+	var userCommunity UserCommunity
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	previousQuery := `SELECT user_id, community_id, joined_at, role FROM user_communities WHERE user_id=$1 AND community_id=$2 FOR UPDATE`
+
+	if err := tx.QueryRowx(previousQuery, userId, communityId).StructScan(&previousUserCommunity); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	query := `UPDATE user_communities SET role=$1 WHERE user_id=$2 AND community_id=$3 RETURNING user_id,community_id,joined_at,role`
+
+	if err := tx.QueryRowx(query, role, userId, communityId).StructScan(&userCommunity); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if audit != nil {
+		audit.Before = previousUserCommunity
+		audit.After = userCommunity
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &userCommunity, nil
+}
+
+func (c *CommunityRepo) GetCommunityModerators(communityId int) ([]User, error) {
+
+	var moderators []User
+
+	query := `SELECT u.id, email, password, username, is_verified, u.role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at 
+	FROM users AS u INNER JOIN user_communities AS uc ON u.id = uc.user_id
+	WHERE uc.community_id=$1 AND uc.role='moderator'
+	ORDER BY uc.joined_at ASC`
+
+	rows, err := c.db.Queryx(query, communityId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var moderator User
+
+		if err := rows.StructScan(&moderator); err != nil {
+			return nil, err
+		}
+
+		moderators = append(moderators, moderator)
+	}
+
+	return moderators, nil
+}
+
+// UpdateCommunityById updates a community and sets community_updated_at, a nil communityTopicIds keeps the current topics
+func (c *CommunityRepo) UpdateCommunityById(communityId int, communityName string, communityDescription *string, communityImage *string, communityVisibility CommunityVisibility, communityTopicIds []int) (*CommunityWithTopics, error) {
+
+	var communityWithTopics CommunityWithTopics
+	var community Community
+	var topics []Topic
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	updateCommunityQuery := `UPDATE communities SET community_name=$1, community_description=$2, community_image=$3, community_visibility=$4, community_updated_at=NOW() 
+	WHERE id=$5 RETURNING
+	id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility`
+
+	if err := tx.QueryRowx(updateCommunityQuery, communityName, communityDescription, communityImage, communityVisibility, communityId).StructScan(&community); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if communityTopicIds != nil {
+
+		if _, err := tx.Exec(`DELETE FROM community_topics WHERE community_id=$1`, communityId); err != nil {
+			rollBackErr = err
+			return nil, rollBackErr
+		}
+
+		createCommunityTopicQuery := `INSERT INTO community_topics(community_id,topic_id) VALUES($1,$2)`
+
+		for _, topicId := range communityTopicIds {
+			if _, err := tx.Exec(createCommunityTopicQuery, communityId, topicId); err != nil {
+				rollBackErr = err
+				return nil, rollBackErr
+			}
+		}
+	}
+
+	topicsQuery := `SELECT id, topic_name 
+	FROM topics WHERE id IN (SELECT topic_id FROM community_topics WHERE community_id=$1)`
+
+	if err := tx.Select(&topics, topicsQuery, communityId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	communityWithTopics.Community = community
+	communityWithTopics.CommunityTopics = topics
+
+	return &communityWithTopics, nil
+}
+
+// DeleteCommunityById deletes a community, its topics, memberships and posts cascade
+func (c *CommunityRepo) DeleteCommunityById(communityId int) error {
+
+	query := `DELETE FROM communities WHERE id=$1`
+
+	_, err := c.db.Exec(query, communityId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateOwnershipTransfer offers the community to another user, replacing any pending offer
+func (c *CommunityRepo) CreateOwnershipTransfer(communityId int, fromUserId int, toUserId int, expiration time.Time) (*CommunityOwnershipTransfer, error) {
+
+	var transfer CommunityOwnershipTransfer
+
+	query := `INSERT INTO community_ownership_transfers(community_id, from_user_id, to_user_id, expiration) VALUES($1,$2,$3,$4)
+	ON CONFLICT(community_id) DO UPDATE SET from_user_id=EXCLUDED.from_user_id, to_user_id=EXCLUDED.to_user_id, 
+	expiration=EXCLUDED.expiration, created_at=NOW()
+	RETURNING community_id, from_user_id, to_user_id, expiration, created_at`
+
+	if err := c.db.QueryRowx(query, communityId, fromUserId, toUserId, expiration).StructScan(&transfer); err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// GetOwnershipTransfer gets the pending, unexpired ownership transfer of a community
+func (c *CommunityRepo) GetOwnershipTransfer(communityId int) (*CommunityOwnershipTransfer, error) {
+
+	var transfer CommunityOwnershipTransfer
+
+	query := `SELECT community_id, from_user_id, to_user_id, expiration, created_at 
+	FROM community_ownership_transfers WHERE community_id=$1 AND expiration > $2`
+
+	if err := c.db.QueryRowx(query, communityId, time.Now()).StructScan(&transfer); err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+func (c *CommunityRepo) GetUserOwnershipTransfers(userId int) ([]CommunityOwnershipTransfer, error) {
+
+	var transfers []CommunityOwnershipTransfer
+
+	query := `SELECT community_id, from_user_id, to_user_id, expiration, created_at 
+	FROM community_ownership_transfers WHERE to_user_id=$1 AND expiration > $2
+	ORDER BY created_at DESC`
+
+	if err := c.db.Select(&transfers, query, userId, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (c *CommunityRepo) DeleteOwnershipTransfer(communityId int) error {
+
+	query := `DELETE FROM community_ownership_transfers WHERE community_id=$1`
+
+	_, err := c.db.Exec(query, communityId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AcceptOwnershipTransfer makes toUserId the owner of the community, the previous owner stays on as a member.
+// Returns sql.ErrNoRows if there is no pending transfer to the user, or the community changed owner since it was offered
+func (c *CommunityRepo) AcceptOwnershipTransfer(communityId int, toUserId int) (*Community, error) {
+
+	var transfer CommunityOwnershipTransfer
+	var community Community
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	transferQuery := `DELETE FROM community_ownership_transfers WHERE community_id=$1 AND to_user_id=$2 AND expiration > $3
+	RETURNING community_id, from_user_id, to_user_id, expiration, created_at`
+
+	if err := tx.QueryRowx(transferQuery, communityId, toUserId, time.Now()).StructScan(&transfer); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	updateOwnerQuery := `UPDATE communities SET community_owner_id=$1, community_updated_at=NOW() 
+	WHERE id=$2 AND community_owner_id=$3 RETURNING
+	id, community_name, community_description, community_image, community_owner_id, community_created_at, community_updated_at, community_visibility`
+
+	if err := tx.QueryRowx(updateOwnerQuery, transfer.ToUserId, transfer.CommunityId, transfer.FromUserId).StructScan(&community); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	// owners are not stored as members of their own communities
+	if _, err := tx.Exec(`DELETE FROM user_communities WHERE user_id=$1 AND community_id=$2`, transfer.ToUserId, transfer.CommunityId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	previousOwnerQuery := `INSERT INTO user_communities(user_id,community_id) VALUES($1,$2) ON CONFLICT DO NOTHING`
+
+	if _, err := tx.Exec(previousOwnerQuery, transfer.FromUserId, transfer.CommunityId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &community, nil
+}
+
+func (c *CommunityRepo) CreateJoinRequest(userId int, communityId int) (*CommunityJoinRequest, error) {
+
+	var joinRequest CommunityJoinRequest
+
+	query := `INSERT INTO community_join_requests(user_id,community_id) VALUES($1,$2) RETURNING user_id,community_id,requested_at`
+
+	if err := c.db.QueryRowx(query, userId, communityId).StructScan(&joinRequest); err != nil {
+		return nil, err
+	}
+
+	return &joinRequest, nil
+}
+
+func (c *CommunityRepo) GetJoinRequest(userId int, communityId int) (*CommunityJoinRequest, error) {
+
+	var joinRequest CommunityJoinRequest
+
+	query := `SELECT user_id, community_id, requested_at FROM community_join_requests WHERE user_id=$1 AND community_id=$2`
+
+	if err := c.db.QueryRowx(query, userId, communityId).StructScan(&joinRequest); err != nil {
+		return nil, err
+	}
+
+	return &joinRequest, nil
+}
+
+func (c *CommunityRepo) DeleteJoinRequest(userId int, communityId int) error {
+
+	query := `DELETE FROM community_join_requests WHERE user_id=$1 AND community_id=$2`
+
+	_, err := c.db.Exec(query, userId, communityId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCommunityJoinRequests gets pending join requests of a community, oldest first
+func (c *CommunityRepo) GetCommunityJoinRequests(communityId int, offset int, limit int) ([]CommunityJoinRequestWithUser, error) {
+
+	var joinRequests []CommunityJoinRequestWithUser
+
+	query := `SELECT jr.user_id, jr.community_id, jr.requested_at,
+	u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at
+	FROM community_join_requests AS jr INNER JOIN users AS u ON jr.user_id = u.id
+	WHERE jr.community_id=$1
+	ORDER BY jr.requested_at ASC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := c.db.Queryx(query, communityId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var joinRequest CommunityJoinRequestWithUser
+
+		if err := rows.Scan(&joinRequest.UserId, &joinRequest.CommunityId, &joinRequest.RequestedAt,
+			&joinRequest.User.Id, &joinRequest.User.Email, &joinRequest.User.Password, &joinRequest.User.Username,
+			&joinRequest.User.IsVerified, &joinRequest.User.Role, &joinRequest.User.UserImage, &joinRequest.User.Bio,
+			&joinRequest.User.Location, &joinRequest.User.DateOfBirth, &joinRequest.User.VerifiedAt,
+			&joinRequest.User.CreatedAt, &joinRequest.User.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		joinRequests = append(joinRequests, joinRequest)
+	}
+
+	return joinRequests, nil
+}
+
+func (c *CommunityRepo) GetCommunityJoinRequestsCount(communityId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(*) FROM community_join_requests WHERE community_id=$1`
+
+	if err := c.db.QueryRow(query, communityId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+// ApproveJoinRequest removes the pending request and adds the user as a member.
+// Returns sql.ErrNoRows if the user has no pending request
+func (c *CommunityRepo) ApproveJoinRequest(userId int, communityId int) (*UserCommunity, error) {
+
+	var userCommunity UserCommunity
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var requestUserId int
+
+	deleteRequestQuery := `DELETE FROM community_join_requests WHERE user_id=$1 AND community_id=$2 RETURNING user_id`
+
+	if err := tx.QueryRowx(deleteRequestQuery, userId, communityId).Scan(&requestUserId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	joinQuery := `INSERT INTO user_communities(user_id,community_id) VALUES($1,$2) RETURNING user_id,community_id,joined_at,role`
+
+	if err := tx.QueryRowx(joinQuery, userId, communityId).StructScan(&userCommunity); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &userCommunity, nil
+}
+
+func (c *CommunityRepo) CreateCommunityInvite(inviteCode string, communityId int, createdById int, maxUses *int, expiration *time.Time) (*CommunityInvite, error) {
+
+	var invite CommunityInvite
+
+	query := `INSERT INTO community_invites(invite_code, community_id, created_by_id, max_uses, expiration) VALUES($1,$2,$3,$4,$5) RETURNING
+	id, invite_code, community_id, created_by_id, max_uses, uses, expiration, created_at`
+
+	if err := c.db.QueryRowx(query, inviteCode, communityId, createdById, maxUses, expiration).StructScan(&invite); err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+func (c *CommunityRepo) GetCommunityInviteById(id int) (*CommunityInvite, error) {
+
+	var invite CommunityInvite
+
+	query := `SELECT id, invite_code, community_id, created_by_id, max_uses, uses, expiration, created_at 
+	FROM community_invites WHERE id=$1`
+
+	if err := c.db.QueryRowx(query, id).StructScan(&invite); err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// GetUsableCommunityInvite gets an invite by its code if it has not expired or run out of uses
+func (c *CommunityRepo) GetUsableCommunityInvite(inviteCode string) (*CommunityInvite, error) {
+
+	var invite CommunityInvite
+
+	query := `SELECT id, invite_code, community_id, created_by_id, max_uses, uses, expiration, created_at 
+	FROM community_invites WHERE invite_code=$1 
+	AND (expiration IS NULL OR expiration > $2) AND (max_uses IS NULL OR uses < max_uses)`
+
+	if err := c.db.QueryRowx(query, inviteCode, time.Now()).StructScan(&invite); err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+func (c *CommunityRepo) GetCommunityInvites(communityId int) ([]CommunityInvite, error) {
+
+	var invites []CommunityInvite
+
+	query := `SELECT id, invite_code, community_id, created_by_id, max_uses, uses, expiration, created_at 
+	FROM community_invites WHERE community_id=$1
+	ORDER BY created_at DESC`
+
+	if err := c.db.Select(&invites, query, communityId); err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+func (c *CommunityRepo) DeleteCommunityInviteById(id int) error {
+
+	query := `DELETE FROM community_invites WHERE id=$1`
+
+	_, err := c.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AcceptCommunityInvite uses up one use of the invite and adds the user as a member, replacing any pending join request.
+// Returns sql.ErrNoRows if the invite expired or ran out of uses in the meantime
+func (c *CommunityRepo) AcceptCommunityInvite(inviteCode string, userId int) (*UserCommunity, error) {
+
+	var userCommunity UserCommunity
+	var communityId int
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	useInviteQuery := `UPDATE community_invites SET uses=uses+1 
+	WHERE invite_code=$1 AND (expiration IS NULL OR expiration > $2) AND (max_uses IS NULL OR uses < max_uses)
+	RETURNING community_id`
+
+	if err := tx.QueryRowx(useInviteQuery, inviteCode, time.Now()).Scan(&communityId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if _, err := tx.Exec(`DELETE FROM community_join_requests WHERE user_id=$1 AND community_id=$2`, userId, communityId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	joinQuery := `INSERT INTO user_communities(user_id,community_id) VALUES($1,$2) RETURNING user_id,community_id,joined_at,role`
+
+	if err := tx.QueryRowx(joinQuery, userId, communityId).StructScan(&userCommunity); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &userCommunity, nil
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CommunityBanType string
+
+const (
+	CommunityBanTypeBan  CommunityBanType = "ban"
+	CommunityBanTypeMute CommunityBanType = "mute"
+)
+
+// a ban keeps the user out of the community, a mute only stops them from posting and commenting
+type CommunityBan struct {
+	CommunityId int              `db:"community_id" json:"community_id"`
+	UserId      int              `db:"user_id" json:"user_id"`
+	BanType     CommunityBanType `db:"ban_type" json:"ban_type"`
+	BanReason   string           `db:"ban_reason" json:"ban_reason"`
+	BannedById  *int             `db:"banned_by_id" json:"banned_by_id"`
+	Expiration  *string          `db:"expiration" json:"expiration"`
+	CreatedAt   string           `db:"created_at" json:"created_at"`
+}
+
+type CommunityBanWithUser struct {
+	CommunityBan
+	User User `json:"user"`
+}
+
+type CommunityBanRepo struct {
+	db *sqlx.DB
+}
+
+func NewCommunityBanRepo(db *sqlx.DB) *CommunityBanRepo {
+	return &CommunityBanRepo{
+		db: db,
+	}
+}
+
+// CreateCommunityBan bans or mutes a user, replacing any earlier ban. banning also removes the user's
+// membership and pending join request
+func (c *CommunityBanRepo) CreateCommunityBan(communityId int, userId int, bannedById int, banType CommunityBanType, banReason string, expiration *time.Time, audit *AuditLogEntry) (*CommunityBan, error) {
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	ban, err := createCommunityBan(tx, communityId, userId, bannedById, banType, banReason, expiration, audit)
+	if err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return ban, nil
+}
+
+func createCommunityBan(tx *sqlx.Tx, communityId int, userId int, bannedById int, banType CommunityBanType, banReason string, expiration *time.Time, audit *AuditLogEntry) (*CommunityBan, error) {
+
+	var ban CommunityBan
+
+	query := `INSERT INTO community_bans(community_id, user_id, ban_type, ban_reason, banned_by_id, expiration) VALUES($1,$2,$3,$4,$5,$6)
+	ON CONFLICT(community_id,user_id) DO UPDATE SET ban_type=EXCLUDED.ban_type, ban_reason=EXCLUDED.ban_reason,
+	banned_by_id=EXCLUDED.banned_by_id, expiration=EXCLUDED.expiration, created_at=NOW()
+	RETURNING community_id, user_id, ban_type, ban_reason, banned_by_id, expiration, created_at`
+
+	if err := tx.QueryRowx(query, communityId, userId, banType, banReason, bannedById, expiration).StructScan(&ban); err != nil {
+		return nil, err
+	}
+
+	if banType == CommunityBanTypeBan {
+
+		if _, err := tx.Exec(`DELETE FROM user_communities WHERE user_id=$1 AND community_id=$2`, userId, communityId); err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM community_join_requests WHERE user_id=$1 AND community_id=$2`, userId, communityId); err != nil {
+			return nil, err
+		}
+	}
+
+	if audit != nil {
+		audit.After = ban
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		return nil, err
+	}
+
+	return &ban, nil
+}
+
+// GetActiveCommunityBan gets the user's ban in the community if it hasn't expired
+func (c *CommunityBanRepo) GetActiveCommunityBan(userId int, communityId int) (*CommunityBan, error) {
+
+	var ban CommunityBan
+
+	query := `SELECT community_id, user_id, ban_type, ban_reason, banned_by_id, expiration, created_at
+	FROM community_bans WHERE user_id=$1 AND community_id=$2 AND (expiration IS NULL OR expiration > $3)`
+
+	if err := c.db.QueryRowx(query, userId, communityId, time.Now()).StructScan(&ban); err != nil {
+		return nil, err
+	}
+
+	return &ban, nil
+}
+
+// GetCommunityBans gets the active bans and mutes of a community, most recent first
+func (c *CommunityBanRepo) GetCommunityBans(communityId int, offset int, limit int) ([]CommunityBanWithUser, error) {
+
+	var bans []CommunityBanWithUser
+
+	query := `SELECT b.community_id, b.user_id, b.ban_type, b.ban_reason, b.banned_by_id, b.expiration, b.created_at,
+	u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, u.created_at, updated_at
+	FROM community_bans AS b INNER JOIN users AS u ON b.user_id = u.id
+	WHERE b.community_id=$1 AND (b.expiration IS NULL OR b.expiration > $2)
+	ORDER BY b.created_at DESC
+	LIMIT $3 OFFSET $4`
+
+	rows, err := c.db.Queryx(query, communityId, time.Now(), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var ban CommunityBanWithUser
+
+		if err := rows.Scan(&ban.CommunityId, &ban.UserId, &ban.BanType, &ban.BanReason, &ban.BannedById, &ban.Expiration, &ban.CreatedAt,
+			&ban.User.Id, &ban.User.Email, &ban.User.Password, &ban.User.Username, &ban.User.IsVerified, &ban.User.Role,
+			&ban.User.UserImage, &ban.User.Bio, &ban.User.Location, &ban.User.DateOfBirth, &ban.User.VerifiedAt,
+			&ban.User.CreatedAt, &ban.User.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		bans = append(bans, ban)
+	}
+
+	return bans, nil
+}
+
+func (c *CommunityBanRepo) GetCommunityBansCount(communityId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(*) FROM community_bans WHERE community_id=$1 AND (expiration IS NULL OR expiration > $2)`
+
+	if err := c.db.QueryRowx(query, communityId, time.Now()).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+func (c *CommunityBanRepo) DeleteCommunityBan(userId int, communityId int, audit *AuditLogEntry) error {
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `DELETE FROM community_bans WHERE user_id=$1 AND community_id=$2`
+
+	if _, err := tx.Exec(query, userId, communityId); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
+	return nil
+}
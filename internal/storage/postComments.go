@@ -1,6 +1,11 @@
 package storage
 
-import "github.com/jmoiron/sqlx"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
 
 type PostComment struct {
 	Id               int     `db:"id" json:"id"`
@@ -10,12 +15,27 @@ type PostComment struct {
 	ParentCommentId  *int    `db:"parent_comment_id" json:"parent_comment_id"`
 	CommentCreatedAt string  `db:"comment_created_at" json:"comment_created_at"`
 	CommentUpdatedAt *string `db:"comment_updated_at" json:"comment_updated_at"`
+	CommentDeletedAt *string `db:"comment_deleted_at" json:"comment_deleted_at"`
 }
 
-type PostCommentLike struct {
-	LikedById          int    `db:"liked_by_id" json:"liked_by_id"`
-	LikedPostCommentId int    `db:"liked_post_comment_id" json:"liked_post_comment_id"`
-	LikedAt            string `db:"liked_at" json:"liked_at"`
+// content of a comment that was replaced by an edit
+type CommentRevision struct {
+	Id                int    `db:"id" json:"id"`
+	CommentId         int    `db:"comment_id" json:"comment_id"`
+	RevisionNumber    int    `db:"revision_number" json:"revision_number"`
+	CommentContent    string `db:"comment_content" json:"comment_content"`
+	EditedById        *int   `db:"edited_by_id" json:"edited_by_id"`
+	RevisionCreatedAt string `db:"revision_created_at" json:"revision_created_at"`
+}
+
+// shown in place of a deleted comment's content
+const DeletedCommentContent = "[deleted]"
+
+type PostCommentVote struct {
+	VoterId   int    `db:"voter_id" json:"voter_id"`
+	CommentId int    `db:"comment_id" json:"comment_id"`
+	VoteValue int    `db:"vote_value" json:"vote_value"`
+	VotedAt   string `db:"voted_at" json:"voted_at"`
 }
 
 type PostCommentWithUser struct {
@@ -25,7 +45,12 @@ type PostCommentWithUser struct {
 
 type PostCommentWithMetaData struct {
 	PostCommentWithUser
-	CommentLikesCount int `json:"comment_likes_count"`
+	CommentUpvotesCount   int            `json:"comment_upvotes_count"`
+	CommentDownvotesCount int            `json:"comment_downvotes_count"`
+	CommentScore          int            `json:"comment_score"`        // upvotes minus downvotes
+	CommentUpvoteRatio    float64        `json:"comment_upvote_ratio"` // share of votes that are upvotes, 0 without votes
+	Reactions             map[string]int `json:"reactions"`            // count of each reaction given to the comment
+	ViewerReactions       []string       `json:"viewer_reactions"`     // reactions the viewer gave, empty for anonymous viewers
 }
 
 type PostCommentRepo struct {
@@ -43,7 +68,7 @@ func (c *PostCommentRepo) CreatePostComment(commentContent string, commentOwnerI
 	var postComment PostComment
 
 	query := `INSERT INTO post_comments(comment_content,comment_owner_id,post_id) VALUES($1,$2,$3) RETURNING 
-	id,comment_content,comment_owner_id,post_id,parent_comment_id,comment_created_at,comment_updated_at`
+	id,comment_content,comment_owner_id,post_id,parent_comment_id,comment_created_at,comment_updated_at,comment_deleted_at`
 
 	if err := c.db.QueryRowx(query, commentContent, commentOwnerId, postId).StructScan(&postComment); err != nil {
 		return nil, err
@@ -57,7 +82,7 @@ func (c *PostCommentRepo) CreateChildPostComment(commentContent string, commentO
 	var postComment PostComment
 
 	query := `INSERT INTO post_comments(comment_content,comment_owner_id,post_id,parent_comment_id) VALUES($1,$2,$3,$4) RETURNING 
-	id,comment_content,comment_owner_id,post_id,parent_comment_id,comment_created_at,comment_updated_at`
+	id,comment_content,comment_owner_id,post_id,parent_comment_id,comment_created_at,comment_updated_at,comment_deleted_at`
 
 	if err := c.db.QueryRowx(query, commentContent, commentOwnerId, postId, parentCommentId).StructScan(&postComment); err != nil {
 		return nil, err
@@ -70,7 +95,7 @@ func (c *PostCommentRepo) GetPostCommentById(id int) (*PostComment, error) {
 
 	var postComment PostComment
 
-	query := `SELECT id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at 
+	query := `SELECT id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at, comment_deleted_at 
 	FROM post_comments WHERE id=$1`
 
 	if err := c.db.QueryRowx(query, id).StructScan(&postComment); err != nil {
@@ -80,80 +105,254 @@ func (c *PostCommentRepo) GetPostCommentById(id int) (*PostComment, error) {
 	return &postComment, nil
 }
 
-func (c *PostCommentRepo) DeletePostCommentById(id int) error {
-
-	query := `DELETE FROM post_comments WHERE id=$1`
+// DeletePostCommentById soft deletes the comment so the replies under it stay in the thread, its content
+// and edit history are removed. audit is nil when the author deletes their own comment
+func (c *PostCommentRepo) DeletePostCommentById(id int, audit *AuditLogEntry) error {
 
-	_, err := c.db.Exec(query, id)
+	tx, err := c.db.Beginx()
 	if err != nil {
 		return err
 	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := softDeleteComment(tx, id); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
 
 	return nil
 }
 
-func (c *PostCommentRepo) CheckCommentLike(userId int, commentId int) (bool, error) {
+func softDeleteComment(tx *sqlx.Tx, id int) error {
+
+	query := `UPDATE post_comments SET comment_content=$1, comment_deleted_at=NOW() WHERE id=$2`
+
+	if _, err := tx.Exec(query, DeletedCommentContent, id); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`DELETE FROM comment_revisions WHERE comment_id=$1`, id)
+	return err
+}
+
+// UpdatePostCommentById edits the comment, the content it replaces is kept as a revision
+func (c *PostCommentRepo) UpdatePostCommentById(id int, commentContent string, editedById int) (*PostComment, error) {
+
+	var postComment PostComment
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// locking the comment keeps revision numbers consecutive under concurrent edits
+	currentQuery := `SELECT id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at, comment_deleted_at 
+	FROM post_comments WHERE id=$1 FOR UPDATE`
+
+	if err := tx.QueryRowx(currentQuery, id).StructScan(&postComment); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	revisionQuery := `INSERT INTO comment_revisions(comment_id, revision_number, comment_content, edited_by_id)
+	VALUES($1, (SELECT COALESCE(MAX(revision_number), 0) + 1 FROM comment_revisions WHERE comment_id=$1), $2, $3)`
+
+	if _, err := tx.Exec(revisionQuery, id, postComment.CommentContent, editedById); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	updateQuery := `UPDATE post_comments SET comment_content=$1, comment_updated_at=NOW() WHERE id=$2 RETURNING 
+	id,comment_content,comment_owner_id,post_id,parent_comment_id,comment_created_at,comment_updated_at,comment_deleted_at`
+
+	if err := tx.QueryRowx(updateQuery, commentContent, id).StructScan(&postComment); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &postComment, nil
+}
+
+// GetCommentRevisions gets the previous versions of a comment, most recent first
+func (c *PostCommentRepo) GetCommentRevisions(commentId int, offset int, limit int) ([]CommentRevision, error) {
+
+	var revisions []CommentRevision
+
+	query := `SELECT id, comment_id, revision_number, comment_content, edited_by_id, revision_created_at
+	FROM comment_revisions WHERE comment_id=$1
+	ORDER BY revision_number DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := c.db.Queryx(query, commentId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	var commentLike PostCommentLike
+	for rows.Next() {
+
+		var revision CommentRevision
 
-	query := `SELECT liked_by_id, liked_post_comment_id, liked_at 
-	FROM post_comment_likes WHERE liked_by_id=$1 AND liked_post_comment_id=$2`
+		if err := rows.StructScan(&revision); err != nil {
+			return nil, err
+		}
 
-	if err := c.db.QueryRowx(query, userId, commentId).StructScan(&commentLike); err != nil {
-		return false, err
+		revisions = append(revisions, revision)
 	}
 
-	return true, nil
+	return revisions, nil
+}
+
+func (c *PostCommentRepo) GetCommentRevisionsCount(commentId int) (int, error) {
+
+	var totalCount int
 
+	query := `SELECT COUNT(*) FROM comment_revisions WHERE comment_id=$1`
+
+	if err := c.db.QueryRowx(query, commentId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
 }
 
-func (c *PostCommentRepo) CreateCommentLike(userId int, commentId int) (*PostCommentLike, error) {
+// GetCommentVote gets the user's vote on the comment
+func (c *PostCommentRepo) GetCommentVote(userId int, commentId int) (*PostCommentVote, error) {
 
-	var commentLike PostCommentLike
+	var commentVote PostCommentVote
 
-	query := `INSERT INTO post_comment_likes(liked_by_id, liked_post_comment_id) VALUES($1,$2) RETURNING liked_by_id,liked_post_comment_id,liked_at`
+	query := `SELECT voter_id, comment_id, vote_value, voted_at 
+	FROM post_comment_votes WHERE voter_id=$1 AND comment_id=$2`
 
-	if err := c.db.QueryRowx(query, userId, commentId).StructScan(&commentLike); err != nil {
+	if err := c.db.QueryRowx(query, userId, commentId).StructScan(&commentVote); err != nil {
 		return nil, err
 	}
 
-	return &commentLike, nil
+	return &commentVote, nil
+}
+
+// VoteComment sets the user's vote on the comment, replacing their earlier vote
+func (c *PostCommentRepo) VoteComment(userId int, commentId int, voteValue int) (*PostCommentVote, error) {
 
+	var commentVote PostCommentVote
+
+	query := `INSERT INTO post_comment_votes(voter_id, comment_id, vote_value) VALUES($1,$2,$3)
+	ON CONFLICT(voter_id,comment_id) DO UPDATE SET vote_value=EXCLUDED.vote_value, voted_at=NOW()
+	RETURNING voter_id, comment_id, vote_value, voted_at`
+
+	if err := c.db.QueryRowx(query, userId, commentId, voteValue).StructScan(&commentVote); err != nil {
+		return nil, err
+	}
+
+	return &commentVote, nil
 }
 
-func (c *PostCommentRepo) RemoveCommentLike(userId int, commentId int) error {
+func (c *PostCommentRepo) RemoveCommentVote(userId int, commentId int) error {
 
-	query := `DELETE FROM post_comment_likes WHERE liked_by_id=$1 AND liked_post_comment_id=$2`
+	query := `DELETE FROM post_comment_votes WHERE voter_id=$1 AND comment_id=$2`
 
-	_, err := c.db.Exec(query, userId, commentId)
-	if err != nil {
+	if _, err := c.db.Exec(query, userId, commentId); err != nil {
 		return err
 	}
 
 	return nil
+}
+
+// confidenceScoreSQL is the lower bound of the 95% Wilson score interval for the share of positive votes,
+// so a comment with few votes ranks below one with as good a share over many votes
+func confidenceScoreSQL(positive string, negative string) string {
+
+	n := fmt.Sprintf("(%s + %s)", positive, negative)
 
+	return fmt.Sprintf(`(CASE WHEN %[3]s = 0 THEN 0 ELSE
+	  ((%[1]s + 1.9208) / %[3]s - 1.96 * SQRT((%[1]s * %[2]s)::float / %[3]s + 0.9604) / %[3]s) / (1 + 3.8416 / %[3]s) END)`, positive, negative, n)
 }
 
-func (c *PostCommentRepo) GetPostComments(postId int, offset int, limit int) ([]PostCommentWithMetaData, error) {
+// controversyScoreSQL grows with the number of votes and with how evenly they are split, content with only
+// upvotes or only downvotes isn't controversial
+func controversyScoreSQL(positive string, negative string) string {
+
+	return fmt.Sprintf(`(CASE WHEN %[1]s = 0 OR %[2]s = 0 THEN 0 ELSE
+	  POWER(%[1]s + %[2]s, LEAST(%[1]s, %[2]s)::float / GREATEST(%[1]s, %[2]s)) END)`, positive, negative)
+}
+
+// commentsOrderBy gets the ORDER BY clause for listing comments, ties are broken by recency
+func commentsOrderBy(sortBy SortByStr) (string, error) {
+
+	upvotesCount := `COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1)`
+	downvotesCount := `COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1)`
+
+	switch sortBy {
+	case SortByNewest:
+		return `comment_created_at DESC, pc.id DESC`, nil
+	case SortByOldest:
+		return `comment_created_at ASC, pc.id ASC`, nil
+	case SortByTop:
+		return `comment_score DESC, comment_created_at DESC, pc.id DESC`, nil
+	case SortByBest:
+		return fmt.Sprintf(`%s DESC, comment_created_at DESC, pc.id DESC`, confidenceScoreSQL(upvotesCount, downvotesCount)), nil
+	case SortByControversial:
+		return fmt.Sprintf(`%s DESC, comment_created_at DESC, pc.id DESC`, controversyScoreSQL(upvotesCount, downvotesCount)), nil
+	default:
+		return "", errors.New("invalid sortBy")
+	}
+}
+
+func (c *PostCommentRepo) GetPostComments(postId int, offset int, limit int, sortBy SortByStr) ([]PostCommentWithMetaData, error) {
 
 	var postComments []PostCommentWithMetaData
 
-	query := `
+	orderBy, err := commentsOrderBy(sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
 	SELECT
-	  	pc.id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at,
+	  	pc.id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at, comment_deleted_at,
 	  	u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, 
-       	verified_at, created_at, updated_at,COUNT(DISTINCT(cl.liked_by_id)) AS comment_likes_count
+       	verified_at, created_at, updated_at,
+       	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) AS comment_upvotes_count,
+       	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_downvotes_count,
+       	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) - COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_score,
+       	COALESCE(COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(cv.voter_id)), 0), 0) AS comment_upvote_ratio
 	FROM
 	  post_comments AS pc
 	  INNER JOIN users AS u ON pc.comment_owner_id = u.id
-	  LEFT JOIN post_comment_likes AS cl ON pc.id = cl.liked_post_comment_id
+	  LEFT JOIN post_comment_votes AS cv ON pc.id = cv.comment_id
 	WHERE
 	  post_id = $1 AND parent_comment_id IS NULL
 	GROUP BY 
 	  pc.id, u.id
 	ORDER BY 
-	  comment_created_at DESC
-	LIMIT $2 OFFSET $3`
+	  %s
+	LIMIT $2 OFFSET $3`, orderBy)
 
 	rows, err := c.db.Queryx(query, postId, limit, offset)
 	if err != nil {
@@ -164,15 +363,12 @@ func (c *PostCommentRepo) GetPostComments(postId int, offset int, limit int) ([]
 	for rows.Next() {
 		var postComment PostCommentWithMetaData
 
-		if err := rows.Scan(&postComment.Id, &postComment.CommentContent, &postComment.CommentOwnerId, &postComment.PostId,
-			&postComment.ParentCommentId, &postComment.CommentCreatedAt, &postComment.CommentUpdatedAt, &postComment.CommentOwner.Id,
-			&postComment.CommentOwner.Email, &postComment.CommentOwner.Password, &postComment.CommentOwner.Username,
-			&postComment.CommentOwner.IsVerified, &postComment.CommentOwner.Role, &postComment.CommentOwner.UserImage, &postComment.CommentOwner.Bio,
-			&postComment.CommentOwner.Location, &postComment.CommentOwner.DateOfBirth, &postComment.CommentOwner.VerifiedAt,
-			&postComment.CommentOwner.CreatedAt, &postComment.CommentOwner.UpdatedAt, &postComment.CommentLikesCount); err != nil {
+		if err := rows.Scan(postCommentWithMetaDataScanDest(&postComment)...); err != nil {
 			return nil, err
 		}
 
+		redactDeletedComment(&postComment)
+
 		postComments = append(postComments, postComment)
 	}
 
@@ -194,25 +390,34 @@ func (c *PostCommentRepo) GetPostCommentsCount(postId int) (int, error) {
 
 }
 
-func (c *PostCommentRepo) GetCommentReplies(commentId int, offset int, limit int) ([]PostCommentWithMetaData, error) {
+func (c *PostCommentRepo) GetCommentReplies(commentId int, offset int, limit int, sortBy SortByStr) ([]PostCommentWithMetaData, error) {
 
 	var commentReplies []PostCommentWithMetaData
 
-	query := `
+	orderBy, err := commentsOrderBy(sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
 	SELECT 
-	    pc.id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at,
+	    pc.id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at, comment_deleted_at,
 		u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, 
-		verified_at, created_at, updated_at,COUNT(DISTINCT(cl.liked_by_id)) AS comment_likes_count 
+		verified_at, created_at, updated_at,
+		COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) AS comment_upvotes_count,
+		COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_downvotes_count,
+		COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) - COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_score,
+		COALESCE(COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(cv.voter_id)), 0), 0) AS comment_upvote_ratio
 	FROM 
 	    post_comments AS pc INNER JOIN users AS u ON pc.comment_owner_id=u.id 
-	    LEFT JOIN post_comment_likes AS cl ON pc.id = cl.liked_post_comment_id	
+	    LEFT JOIN post_comment_votes AS cv ON pc.id = cv.comment_id
 	WHERE 
 	    parent_comment_id=$1
 	GROUP BY 
 	    pc.id,u.id
 	ORDER BY 
-	    comment_created_at DESC
-	LIMIT $2 OFFSET $3`
+	    %s
+	LIMIT $2 OFFSET $3`, orderBy)
 
 	rows, err := c.db.Queryx(query, commentId, limit, offset)
 	if err != nil {
@@ -224,15 +429,12 @@ func (c *PostCommentRepo) GetCommentReplies(commentId int, offset int, limit int
 
 		var postComment PostCommentWithMetaData
 
-		if err := rows.Scan(&postComment.Id, &postComment.CommentContent, &postComment.CommentOwnerId, &postComment.PostId,
-			&postComment.ParentCommentId, &postComment.CommentCreatedAt, &postComment.CommentUpdatedAt, &postComment.CommentOwner.Id,
-			&postComment.CommentOwner.Email, &postComment.CommentOwner.Password, &postComment.CommentOwner.Username,
-			&postComment.CommentOwner.IsVerified, &postComment.CommentOwner.Role, &postComment.CommentOwner.UserImage, &postComment.CommentOwner.Bio,
-			&postComment.CommentOwner.Location, &postComment.CommentOwner.DateOfBirth, &postComment.CommentOwner.VerifiedAt,
-			&postComment.CommentOwner.CreatedAt, &postComment.CommentOwner.UpdatedAt, &postComment.CommentLikesCount); err != nil {
+		if err := rows.Scan(postCommentWithMetaDataScanDest(&postComment)...); err != nil {
 			return nil, err
 		}
 
+		redactDeletedComment(&postComment)
+
 		commentReplies = append(commentReplies, postComment)
 
 	}
@@ -254,3 +456,91 @@ func (c *PostCommentRepo) GetCommentRepliesCount(commentId int) (int, error) {
 	return totalCommentRepliesCount, nil
 
 }
+
+// deleted comments stay in threads as placeholders, without their author
+func redactDeletedComment(postComment *PostCommentWithMetaData) {
+
+	if postComment.CommentDeletedAt == nil {
+		return
+	}
+
+	postComment.CommentContent = DeletedCommentContent
+	postComment.CommentOwnerId = 0
+	postComment.CommentOwner = User{}
+}
+
+// scan destinations for the comment and comment owner columns shared by every PostCommentWithMetaData query,
+// followed by the vote counts
+func postCommentWithMetaDataScanDest(postComment *PostCommentWithMetaData) []interface{} {
+	return []interface{}{
+		&postComment.Id, &postComment.CommentContent, &postComment.CommentOwnerId, &postComment.PostId,
+		&postComment.ParentCommentId, &postComment.CommentCreatedAt, &postComment.CommentUpdatedAt, &postComment.CommentDeletedAt,
+		&postComment.CommentOwner.Id,
+		&postComment.CommentOwner.Email, &postComment.CommentOwner.Password, &postComment.CommentOwner.Username,
+		&postComment.CommentOwner.IsVerified, &postComment.CommentOwner.Role, &postComment.CommentOwner.UserImage, &postComment.CommentOwner.Bio,
+		&postComment.CommentOwner.Location, &postComment.CommentOwner.DateOfBirth, &postComment.CommentOwner.VerifiedAt,
+		&postComment.CommentOwner.CreatedAt, &postComment.CommentOwner.UpdatedAt, &postComment.CommentUpvotesCount,
+		&postComment.CommentDownvotesCount, &postComment.CommentScore, &postComment.CommentUpvoteRatio,
+	}
+}
+
+// GetUserComments gets comments and replies written by a user, most recent first, leaving out comments in private communities
+func (c *PostCommentRepo) GetUserComments(userId int, offset int, limit int) ([]PostCommentWithMetaData, error) {
+
+	var postComments []PostCommentWithMetaData
+
+	query := `
+	SELECT
+	  	pc.id, comment_content, comment_owner_id, post_id, parent_comment_id, comment_created_at, comment_updated_at, comment_deleted_at,
+	  	u.id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, 
+       	verified_at, created_at, updated_at,
+       	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) AS comment_upvotes_count,
+       	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_downvotes_count,
+       	COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1) - COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = -1) AS comment_score,
+       	COALESCE(COUNT(DISTINCT(cv.voter_id)) FILTER (WHERE cv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(cv.voter_id)), 0), 0) AS comment_upvote_ratio
+	FROM
+	  post_comments AS pc
+	  INNER JOIN users AS u ON pc.comment_owner_id = u.id
+	  LEFT JOIN post_comment_votes AS cv ON pc.id = cv.comment_id
+	WHERE
+	  comment_owner_id = $1 AND comment_deleted_at IS NULL
+	  AND post_id NOT IN (SELECT p.id FROM posts AS p INNER JOIN communities AS c ON p.post_community_id = c.id WHERE c.community_visibility='private')
+	GROUP BY 
+	  pc.id, u.id
+	ORDER BY 
+	  comment_created_at DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := c.db.Queryx(query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var postComment PostCommentWithMetaData
+
+		if err := rows.Scan(postCommentWithMetaDataScanDest(&postComment)...); err != nil {
+			return nil, err
+		}
+
+		postComments = append(postComments, postComment)
+	}
+
+	return postComments, nil
+}
+
+func (c *PostCommentRepo) GetUserCommentsCount(userId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(*) FROM post_comments WHERE comment_owner_id=$1 AND comment_deleted_at IS NULL
+	AND post_id NOT IN (SELECT p.id FROM posts AS p INNER JOIN communities AS c ON p.post_community_id = c.id WHERE c.community_visibility='private')`
+
+	if err := c.db.QueryRowx(query, userId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
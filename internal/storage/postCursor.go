@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// PostCursor continues a posts listing after the post with Id, using the value the listing is sorted by:
+// CreatedAt for new, Score for top and ActivityScore for hot, the hot score the post was listed with. hot
+// listings are ranked as of SnapshotAt, when the hot scores of their first page were computed, so the ranking
+// doesn't shift between pages as time passes. once the scores are recomputed they are ranked from the counters
+// as of SnapshotAt, leaving out posts created after it
+type PostCursor struct {
+	SortBy        SortByStr `json:"sort_by"`
+	Id            int       `json:"id"`
+	CreatedAt     string    `json:"created_at,omitempty"`
+	Score         int       `json:"score,omitempty"`
+	ActivityScore float64   `json:"activity_score,omitempty"`
+	SnapshotAt    string    `json:"snapshot_at,omitempty"`
+}
+
+func (c PostCursor) Encode() string {
+
+	cursorJSON, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+func DecodePostCursor(cursor string) (*PostCursor, error) {
+
+	var postCursor PostCursor
+
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(cursorJSON, &postCursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	switch postCursor.SortBy {
+	case SortByNewest:
+		if postCursor.CreatedAt == "" {
+			return nil, ErrInvalidCursor
+		}
+	case SortByTop:
+	case SortByRelevance:
+		if postCursor.SnapshotAt == "" {
+			return nil, ErrInvalidCursor
+		}
+	default:
+		return nil, ErrInvalidCursor
+	}
+
+	if postCursor.Id == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &postCursor, nil
+}
+
+// GetCommunityPostsByCursor is GetCommunityPosts paginated by cursor, the returned cursor continues after
+// the last post and is nil at the end of the listing
+func (p *PostRepo) GetCommunityPostsByCursor(communityId int, sortBy SortByStr, search string, cursor *PostCursor, limit int) ([]PostWithMetaData, *PostCursor, error) {
+
+	whereClause, args := communityPostsFilter(communityId, search)
+
+	return p.getPosts(whereClause, args, sortBy, cursor, 0, limit)
+}
+
+// GetUserPostsFeedByCursor is GetUserPostsFeed paginated by cursor
+func (p *PostRepo) GetUserPostsFeedByCursor(userId int, n int, sortBy SortByStr, cursor *PostCursor, limit int) ([]PostWithMetaData, *PostCursor, error) {
+
+	whereClause, args := userPostsFeedFilter(userId, n)
+
+	return p.getPosts(whereClause, args, sortBy, cursor, 0, limit)
+}
+
+// GetPostsFeedByCursor is GetPostsFeed paginated by cursor
+func (p *PostRepo) GetPostsFeedByCursor(n int, sortBy SortByStr, cursor *PostCursor, limit int) ([]PostWithMetaData, *PostCursor, error) {
+
+	whereClause, args := postsFeedFilter(n)
+
+	return p.getPosts(whereClause, args, sortBy, cursor, 0, limit)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestPostCursorRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		cursor PostCursor
+	}{
+		{name: "new", cursor: PostCursor{SortBy: SortByNewest, Id: 42, CreatedAt: "2025-01-02T03:04:05Z"}},
+		{name: "top", cursor: PostCursor{SortBy: SortByTop, Id: 42, Score: -3}},
+		{name: "top with a score of 0", cursor: PostCursor{SortBy: SortByTop, Id: 42}},
+		{name: "hot", cursor: PostCursor{SortBy: SortByRelevance, Id: 42, ActivityScore: 0.000123456789, SnapshotAt: "2025-01-02T03:04:05Z"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			decoded, err := DecodePostCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("DecodePostCursor() error = %v", err)
+			}
+
+			// the hot score has to survive exactly for the keyset to continue right after the post
+			if *decoded != tt.cursor {
+				t.Errorf("DecodePostCursor() = %+v, want %+v", *decoded, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodePostCursorInvalid(t *testing.T) {
+
+	encode := func(cursorJSON string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(cursorJSON))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "not json", cursor: encode("cursor")},
+		{name: "unknown sort", cursor: encode(`{"sort_by":"best","id":42}`)},
+		{name: "new without created at", cursor: encode(`{"sort_by":"new","id":42}`)},
+		{name: "hot without snapshot", cursor: encode(`{"sort_by":"hot","id":42,"activity_score":1.5}`)},
+		{name: "without id", cursor: encode(`{"sort_by":"top","score":3}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if _, err := DecodePostCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodePostCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// posts older than this aren't hot anymore, their hot score stays 0
+	hotScoreWindow = 7 * 24 * time.Hour
+
+	// weights of the post_stats counters in a post's engagement
+	hotScoreVoteWeight     = 0.3
+	hotScoreCommentWeight  = 0.5
+	hotScoreBookmarkWeight = 0.2
+)
+
+// hotScoreSQL is the hot score of a post as of the timestamp at, from the counters in post_stats. its
+// engagement decays with the square of its age in minutes, a float so that it survives a cursor round trip
+func hotScoreSQL(at string) string {
+	return fmt.Sprintf(`(CASE WHEN p.post_created_at > %[1]s - INTERVAL '%[2]d seconds'
+	THEN (%[3]v * ps.score + %[4]v * ps.comments_count + %[5]v * ps.bookmarks_count) /
+	POWER(GREATEST(EXTRACT(EPOCH FROM (%[1]s - p.post_created_at)) / 60, 1), 2)
+	ELSE 0 END)::float8`, at, int(hotScoreWindow.Seconds()), hotScoreVoteWeight, hotScoreCommentWeight, hotScoreBookmarkWeight)
+}
+
+// hotScore is hotScoreSQL computed in go, for a post with the given counters that is age old
+func hotScore(score int, commentsCount int, bookmarksCount int, age time.Duration) float64 {
+
+	if age >= hotScoreWindow {
+		return 0
+	}
+
+	engagement := hotScoreVoteWeight*float64(score) + hotScoreCommentWeight*float64(commentsCount) + hotScoreBookmarkWeight*float64(bookmarksCount)
+
+	return engagement / math.Pow(math.Max(age.Minutes(), 1), 2)
+}
+
+// UpdateHotScores recomputes the stored hot scores of the posts still within the hot window along with
+// posts that just left it, all as of the same time
+func (p *PostRepo) UpdateHotScores() (int64, error) {
+
+	query := fmt.Sprintf(`UPDATE post_stats AS ps SET hot_score = %s, hot_score_updated_at = NOW()::timestamp
+	FROM posts AS p
+	WHERE p.id = ps.post_id AND (p.post_created_at > NOW()::timestamp - INTERVAL '%d seconds' OR ps.hot_score <> 0)`, hotScoreSQL(`NOW()::timestamp`), int(hotScoreWindow.Seconds()))
+
+	result, err := p.db.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// getHotScoresUpdatedAt gets when the hot scores were last recomputed, empty when they never were
+func (p *PostRepo) getHotScoresUpdatedAt() (string, error) {
+
+	var updatedAt sql.NullString
+
+	if err := p.db.QueryRow(`SELECT MAX(hot_score_updated_at) FROM post_stats`).Scan(&updatedAt); err != nil {
+		return "", err
+	}
+
+	return updatedAt.String, nil
+}
+
+// getPosts gets up to limit posts matching whereClause (which uses args) sorted by sortBy, either skipping
+// skip posts or continuing after cursor. the counts come from post_stats, so new and top are indexed and hot
+// orders by the stored hot score. a hot cursor outlasting the scores it started from is ranked from the
+// counters as of its snapshot instead. the returned cursor continues after the last post, nil at the end
+func (p *PostRepo) getPosts(whereClause string, args []interface{}, sortBy SortByStr, cursor *PostCursor, skip int, limit int) ([]PostWithMetaData, *PostCursor, error) {
+
+	var posts []PostWithMetaData
+	var snapshotAt string
+
+	// the last post of the page becomes the cursor, so a page can't be empty
+	if limit < 1 {
+		return nil, nil, errors.New("limit should be at least 1")
+	}
+
+	if cursor != nil && cursor.SortBy != sortBy {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	activityScoreColumn := `0.0::float8`
+	var orderBy string
+
+	switch sortBy {
+	case SortByNewest:
+
+		if cursor != nil {
+			args = append(args, cursor.CreatedAt, cursor.Id)
+			whereClause += fmt.Sprintf(` AND (p.post_created_at, p.id) < ($%d::timestamp, $%d)`, len(args)-1, len(args))
+		}
+
+		orderBy = `p.post_created_at DESC, p.id DESC`
+
+	case SortByTop:
+
+		if cursor != nil {
+			args = append(args, cursor.Score, cursor.Id)
+			whereClause += fmt.Sprintf(` AND (ps.score, ps.post_id) < ($%d, $%d)`, len(args)-1, len(args))
+		}
+
+		orderBy = `ps.score DESC, ps.post_id DESC`
+
+	case SortByRelevance:
+
+		hotScoresUpdatedAt, err := p.getHotScoresUpdatedAt()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if cursor != nil {
+			snapshotAt = cursor.SnapshotAt
+		} else if hotScoresUpdatedAt != "" {
+			snapshotAt = hotScoresUpdatedAt
+		} else if err := p.db.QueryRow(`SELECT NOW()::timestamp`).Scan(&snapshotAt); err != nil {
+			return nil, nil, err
+		}
+
+		// posts created since the scores were computed are stored with a hot score of 0 until the next run
+		activityScoreColumn = `ps.hot_score`
+		orderBy = `ps.hot_score DESC, ps.post_id DESC`
+
+		if snapshotAt != hotScoresUpdatedAt {
+
+			args = append(args, snapshotAt)
+			snapshotParam := fmt.Sprintf(`$%d::timestamp`, len(args))
+
+			// posts created after the snapshot would be ranked by a time in their past
+			whereClause += fmt.Sprintf(` AND p.post_created_at < %s`, snapshotParam)
+
+			activityScoreColumn = hotScoreSQL(snapshotParam)
+			orderBy = `activity_score DESC, p.id DESC`
+		}
+
+		// the cursor carries the hot score its post was listed with, so the next page continues below it
+		// even when the scores were recomputed in between
+		if cursor != nil {
+			args = append(args, cursor.ActivityScore, cursor.Id)
+			whereClause += fmt.Sprintf(` AND (%s, p.id) < ($%d::float8, $%d)`, activityScoreColumn, len(args)-1, len(args))
+		}
+
+	default:
+		return nil, nil, errors.New("invalid sortBy")
+	}
+
+	// one more post than asked for tells whether the listing goes on
+	args = append(args, limit+1, skip)
+
+	query := fmt.Sprintf(`SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
+  p.post_community_id,p.post_created_at,p.post_updated_at,
+  u.id,u.email,u.password,u.username,u.is_verified,u.role,
+  u.user_image,u.bio,u.location,u.date_of_birth,u.verified_at,
+  u.created_at,u.updated_at,
+  ps.upvotes_count AS post_upvotes_count,
+  ps.downvotes_count AS post_downvotes_count,
+  ps.score AS post_score,
+  COALESCE(ps.upvotes_count::float / NULLIF(ps.upvotes_count + ps.downvotes_count, 0), 0) AS post_upvote_ratio,
+  ps.comments_count AS post_comments_count,
+  ps.bookmarks_count AS post_bookmarks_count,
+  %s AS activity_score
+FROM posts AS p INNER JOIN users AS u ON p.post_owner_id=u.id
+INNER JOIN post_stats AS ps ON p.id = ps.post_id
+%s
+ORDER BY %s
+LIMIT $%d OFFSET $%d`, activityScoreColumn, whereClause, orderBy, len(args)-1, len(args))
+
+	rows, err := p.db.Queryx(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var activityScores []float64
+
+	for rows.Next() {
+
+		var postWithMetaData PostWithMetaData
+		var activityScore float64
+
+		if err := rows.Scan(append(postWithMetaDataScanDest(&postWithMetaData), &activityScore)...); err != nil {
+			return nil, nil, err
+		}
+
+		posts = append(posts, postWithMetaData)
+		activityScores = append(activityScores, activityScore)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	hasMore := len(posts) > limit
+	if hasMore {
+		posts = posts[:limit]
+	}
+
+	if err := p.setPostsImages(posts); err != nil {
+		return nil, nil, err
+	}
+
+	if !hasMore {
+		return posts, nil, nil
+	}
+
+	last := posts[len(posts)-1]
+	nextCursor := PostCursor{
+		SortBy:        sortBy,
+		Id:            last.Id,
+		CreatedAt:     last.PostCreatedAt,
+		Score:         last.PostScore,
+		ActivityScore: activityScores[len(posts)-1],
+		SnapshotAt:    snapshotAt,
+	}
+
+	return posts, &nextCursor, nil
+}
+
+func (p *PostRepo) setPostsImages(posts []PostWithMetaData) error {
+
+	for i := range posts {
+
+		postImages, err := p.getPostImages(posts[i].Id)
+		if err != nil {
+			return err
+		}
+
+		posts[i].PostImages = postImages
+	}
+
+	return nil
+}
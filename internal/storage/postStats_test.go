@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestHotScoreOrdering(t *testing.T) {
+
+	type post struct {
+		name           string
+		score          int
+		commentsCount  int
+		bookmarksCount int
+		age            time.Duration
+	}
+
+	// listed hottest first
+	posts := []post{
+		{name: "fresh with a few votes", score: 5, age: 10 * time.Minute},
+		{name: "hour old and busy", score: 40, commentsCount: 30, bookmarksCount: 10, age: time.Hour},
+		{name: "hour old with votes only", score: 40, age: time.Hour},
+		{name: "day old and very busy", score: 500, commentsCount: 200, bookmarksCount: 100, age: 24 * time.Hour},
+		{name: "just created", age: 0},
+		{name: "downvoted", score: -10, age: 2 * time.Hour},
+	}
+
+	got := make([]post, len(posts))
+	copy(got, posts)
+
+	// walk the table backwards so a stable sort can't pass by keeping the order it was given
+	for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+		got[i], got[j] = got[j], got[i]
+	}
+
+	sort.SliceStable(got, func(i, j int) bool {
+		return hotScore(got[i].score, got[i].commentsCount, got[i].bookmarksCount, got[i].age) >
+			hotScore(got[j].score, got[j].commentsCount, got[j].bookmarksCount, got[j].age)
+	})
+
+	for i := range posts {
+		if got[i].name != posts[i].name {
+			t.Errorf("position %d = %q, want %q", i, got[i].name, posts[i].name)
+		}
+	}
+}
+
+func TestHotScore(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		score          int
+		commentsCount  int
+		bookmarksCount int
+		age            time.Duration
+		want           float64
+	}{
+		{name: "no engagement", age: time.Hour, want: 0},
+		{name: "weighted counters", score: 10, commentsCount: 2, bookmarksCount: 5, age: time.Minute, want: 0.3*10 + 0.5*2 + 0.2*5},
+		{name: "decays with the square of the age", score: 10, age: 10 * time.Minute, want: 3.0 / 100},
+		{name: "younger than a minute counts as a minute", score: 10, age: 10 * time.Second, want: 3},
+		{name: "just inside the window", score: 10, age: hotScoreWindow - time.Minute, want: 3 / ((7*24*60 - 1) * (7*24*60 - 1.0))},
+		{name: "out of the window", score: 1000, age: hotScoreWindow, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got := hotScore(tt.score, tt.commentsCount, tt.bookmarksCount, tt.age)
+
+			if diff := got - tt.want; diff > 1e-12 || diff < -1e-12 {
+				t.Errorf("hotScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
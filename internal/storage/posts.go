@@ -1,10 +1,10 @@
 package storage
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Post struct {
@@ -17,16 +17,23 @@ type Post struct {
 	PostUpdatedAt   *string `db:"post_updated_at" json:"post_updated_at"`
 }
 
-type PostLike struct {
-	LikedById   int    `db:"liked_by_id" json:"liked_by_id"`
-	LikedPostId int    `db:"liked_post_id" json:"liked_post_id"`
-	LikedAt     string `db:"liked_at" json:"liked_at"`
+const (
+	VoteUp   = 1
+	VoteDown = -1
+)
+
+type PostVote struct {
+	VoterId   int    `db:"voter_id" json:"voter_id"`
+	PostId    int    `db:"post_id" json:"post_id"`
+	VoteValue int    `db:"vote_value" json:"vote_value"`
+	VotedAt   string `db:"voted_at" json:"voted_at"`
 }
 
 type PostBookmark struct {
 	BookmarkedById   int    `db:"bookmarked_by_id" json:"bookmarked_by_id"`
 	BookmarkedPostId int    `db:"bookmarked_post_id" json:"bookmarked_post_id"`
 	BookmarkedAt     string `db:"bookmarked_at" json:"bookmarked_at"`
+	CollectionId     *int   `db:"collection_id" json:"collection_id"`
 }
 
 type PostImage struct {
@@ -35,6 +42,18 @@ type PostImage struct {
 	PostId       int    `db:"post_id" json:"post_id"`
 }
 
+// a post revision is a version of the post that was replaced by an edit
+type PostRevision struct {
+	Id                int            `db:"id" json:"id"`
+	PostId            int            `db:"post_id" json:"post_id"`
+	RevisionNumber    int            `db:"revision_number" json:"revision_number"`
+	PostTitle         string         `db:"post_title" json:"post_title"`
+	PostContent       string         `db:"post_content" json:"post_content"`
+	PostImageUrls     pq.StringArray `db:"post_image_urls" json:"post_image_urls"`
+	EditedById        *int           `db:"edited_by_id" json:"edited_by_id"`
+	RevisionCreatedAt string         `db:"revision_created_at" json:"revision_created_at"`
+}
+
 type PostWithImages struct {
 	Post
 	PostImages []PostImage `json:"post_images"`
@@ -42,10 +61,17 @@ type PostWithImages struct {
 
 type PostWithMetaData struct {
 	PostWithImages
-	PostOwner          User `json:"post_owner"`
-	PostLikesCount     int  `json:"post_likes_count"`
-	PostCommentsCount  int  `json:"post_comments_count"`
-	PostBookmarksCount int  `json:"post_bookmarks_count"`
+	PostOwner          User           `json:"post_owner"`
+	PostUpvotesCount   int            `json:"post_upvotes_count"`
+	PostDownvotesCount int            `json:"post_downvotes_count"`
+	PostScore          int            `json:"post_score"`        // upvotes minus downvotes
+	PostUpvoteRatio    float64        `json:"post_upvote_ratio"` // share of votes that are upvotes, 0 without votes
+	PostCommentsCount  int            `json:"post_comments_count"`
+	PostBookmarksCount int            `json:"post_bookmarks_count"`
+	ViewerVote         int            `json:"viewer_vote"`      // 1, -1 or 0 when the viewer hasn't voted or is anonymous
+	IsBookmarked       bool           `json:"is_bookmarked"`    // by the viewer, false for anonymous viewers
+	Reactions          map[string]int `json:"reactions"`        // count of each reaction given to the post
+	ViewerReactions    []string       `json:"viewer_reactions"` // reactions the viewer gave, empty for anonymous viewers
 }
 
 type PostRepo struct {
@@ -139,65 +165,324 @@ func (p *PostRepo) GetPostById(id int) (*Post, error) {
 
 }
 
-func (p *PostRepo) DeletePostById(id int) error {
+// GetPostWithMetaDataById gets a post along with its images, owner and counts
+func (p *PostRepo) GetPostWithMetaDataById(id int) (*PostWithMetaData, error) {
+
+	var post PostWithMetaData
+
+	query := `SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
+  p.post_community_id,p.post_created_at,p.post_updated_at,
+  u.id,u.email,u.password,u.username,u.is_verified,u.role,
+  u.user_image,u.bio,u.location,u.date_of_birth,u.verified_at,
+  u.created_at,u.updated_at,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) AS post_upvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_downvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) - COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_score,
+  COALESCE(COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(pv.voter_id)), 0), 0) AS post_upvote_ratio,
+  COUNT(DISTINCT(pc.id)) AS post_comments_count,
+  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count
+FROM posts AS p INNER JOIN users AS u ON p.post_owner_id=u.id
+LEFT JOIN post_votes AS pv ON p.id = pv.post_id
+LEFT JOIN post_comments AS pc ON p.id = pc.post_id AND pc.parent_comment_id IS NULL
+LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
+WHERE p.id=$1
+GROUP BY p.id,u.id`
 
-	query := `DELETE FROM posts WHERE id=$1`
+	if err := p.db.QueryRowx(query, id).Scan(postWithMetaDataScanDest(&post)...); err != nil {
+		return nil, err
+	}
 
-	_, err := p.db.Exec(query, id)
+	postImages, err := p.getPostImages(post.Id)
 	if err != nil {
+		return nil, err
+	}
+
+	post.PostImages = postImages
+
+	return &post, nil
+}
+
+// SetPostsViewerState sets the viewer's vote on each of the posts and whether they bookmarked it
+func (p *PostRepo) SetPostsViewerState(userId int, posts []PostWithMetaData) error {
+
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIds := make([]int64, len(posts))
+	for i, post := range posts {
+		postIds[i] = int64(post.Id)
+	}
+
+	var votes []PostVote
+	var bookmarkedPostIds []int
+
+	votesQuery := `SELECT voter_id, post_id, vote_value, voted_at FROM post_votes WHERE voter_id=$1 AND post_id = ANY($2)`
+
+	if err := p.db.Select(&votes, votesQuery, userId, pq.Array(postIds)); err != nil {
 		return err
 	}
 
-	return nil
+	bookmarksQuery := `SELECT bookmarked_post_id FROM post_bookmarks WHERE bookmarked_by_id=$1 AND bookmarked_post_id = ANY($2)`
+
+	if err := p.db.Select(&bookmarkedPostIds, bookmarksQuery, userId, pq.Array(postIds)); err != nil {
+		return err
+	}
+
+	viewerVotes := make(map[int]int, len(votes))
+	for _, vote := range votes {
+		viewerVotes[vote.PostId] = vote.VoteValue
+	}
 
+	isBookmarked := make(map[int]bool, len(bookmarkedPostIds))
+	for _, postId := range bookmarkedPostIds {
+		isBookmarked[postId] = true
+	}
+
+	for i := range posts {
+		posts[i].ViewerVote = viewerVotes[posts[i].Id]
+		posts[i].IsBookmarked = isBookmarked[posts[i].Id]
+	}
+
+	return nil
 }
 
-func (p *PostRepo) CheckPostLike(userId int, postId int) (bool, error) {
+// UpdatePostById edits the post, storing the version it replaces as a revision first.
+// images are only replaced when postImageUrls is not nil
+func (p *PostRepo) UpdatePostById(id int, postTitle string, postContent string, postImageUrls []string, editedById int) (*PostWithImages, error) {
 
-	var postLike PostLike
+	var post Post
+	var currentImageUrls []string
+	var postImages []PostImage
 
-	query := `SELECT liked_by_id,liked_post_id,liked_at 
-	FROM post_likes WHERE liked_by_id=$1 AND liked_post_id=$2`
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
 
-	if err := p.db.QueryRowx(query, userId, postId).StructScan(&postLike); err != nil {
-		return false, err
+	// lock the post so concurrent edits get consecutive revision numbers
+	currentPostQuery := `SELECT id, post_title, post_content, post_owner_id, post_community_id, post_created_at, post_updated_at 
+	FROM posts WHERE id=$1 FOR UPDATE`
+
+	if err := tx.QueryRowx(currentPostQuery, id).StructScan(&post); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
 	}
 
-	return true, nil
+	if err := tx.Select(&currentImageUrls, `SELECT post_image_url FROM post_images WHERE post_id=$1 ORDER BY id ASC`, id); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	revisionQuery := `INSERT INTO post_revisions(post_id, revision_number, post_title, post_content, post_image_urls, edited_by_id)
+	VALUES($1, (SELECT COALESCE(MAX(revision_number), 0) + 1 FROM post_revisions WHERE post_id=$1), $2, $3, $4, $5)`
+
+	if _, err := tx.Exec(revisionQuery, post.Id, post.PostTitle, post.PostContent, pq.Array(currentImageUrls), editedById); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	updateQuery := `UPDATE posts SET post_title=$1, post_content=$2, post_updated_at=NOW() WHERE id=$3
+	RETURNING id, post_title, post_content, post_owner_id, post_community_id, post_created_at, post_updated_at`
+
+	if err := tx.QueryRowx(updateQuery, postTitle, postContent, id).StructScan(&post); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if postImageUrls != nil {
+
+		if _, err := tx.Exec(`DELETE FROM post_images WHERE post_id=$1`, id); err != nil {
+			rollBackErr = err
+			return nil, rollBackErr
+		}
+
+		for _, postImageUrl := range postImageUrls {
+
+			if _, err := tx.Exec(`INSERT INTO post_images(post_image_url,post_id) VALUES($1,$2)`, postImageUrl, id); err != nil {
+				rollBackErr = err
+				return nil, rollBackErr
+			}
+		}
+	}
+
+	if err := tx.Select(&postImages, `SELECT id, post_image_url, post_id FROM post_images WHERE post_id=$1 ORDER BY id ASC`, id); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &PostWithImages{Post: post, PostImages: postImages}, nil
 }
 
-func (p *PostRepo) CreatePostLike(userId int, postId int) (*PostLike, error) {
+// GetPostRevisions gets the previous versions of a post, most recent first
+func (p *PostRepo) GetPostRevisions(postId int, offset int, limit int) ([]PostRevision, error) {
 
-	var postLike PostLike
+	var revisions []PostRevision
 
-	query := `INSERT INTO post_likes(liked_by_id,liked_post_id) VALUES($1,$2) RETURNING liked_by_id,liked_post_id,liked_at`
+	query := `SELECT id, post_id, revision_number, post_title, post_content, post_image_urls, edited_by_id, revision_created_at
+	FROM post_revisions WHERE post_id=$1
+	ORDER BY revision_number DESC
+	LIMIT $2 OFFSET $3`
 
-	if err := p.db.QueryRowx(query, userId, postId).StructScan(&postLike); err != nil {
+	rows, err := p.db.Queryx(query, postId, limit, offset)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
 
-	return &postLike, nil
+		var revision PostRevision
 
+		if err := rows.StructScan(&revision); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
 }
 
-func (p *PostRepo) RemovePostLike(userId int, postId int) error {
+func (p *PostRepo) GetPostRevisionsCount(postId int) (int, error) {
+
+	var totalCount int
 
-	query := `DELETE FROM post_likes WHERE liked_by_id=$1 AND liked_post_id=$2`
+	query := `SELECT COUNT(*) FROM post_revisions WHERE post_id=$1`
 
-	_, err := p.db.Exec(query, userId, postId)
+	if err := p.db.QueryRow(query, postId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+// DeletePostById deletes the post, audit is nil when the author deletes their own post. it returns the
+// urls of the post's images (including those of its revisions) that nothing else uses anymore
+func (p *PostRepo) DeletePostById(id int, audit *AuditLogEntry) ([]string, error) {
+
+	tx, err := p.db.Beginx()
 	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	unusedImageUrls, err := deletePost(tx, id, audit)
+	if err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return unusedImageUrls, nil
+}
+
+func deletePost(tx *sqlx.Tx, id int, audit *AuditLogEntry) ([]string, error) {
+
+	var imageUrls []string
+	var unusedImageUrls []string
+
+	imagesQuery := `SELECT post_image_url FROM post_images WHERE post_id=$1
+	UNION
+	SELECT UNNEST(post_image_urls) FROM post_revisions WHERE post_id=$1`
+
+	if err := tx.Select(&imageUrls, imagesQuery, id); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM posts WHERE id=$1`, id); err != nil {
+		return nil, err
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		return nil, err
+	}
+
+	if len(imageUrls) == 0 {
+		return nil, nil
+	}
+
+	// the same url can be used by other posts, communities and profiles
+	unusedQuery := `SELECT url FROM UNNEST($1::text[]) AS url
+	WHERE NOT EXISTS (SELECT 1 FROM post_images WHERE post_image_url = url)
+	AND NOT EXISTS (SELECT 1 FROM post_revisions WHERE url = ANY(post_image_urls))
+	AND NOT EXISTS (SELECT 1 FROM communities WHERE community_image = url)
+	AND NOT EXISTS (SELECT 1 FROM users WHERE user_image = url)`
+
+	if err := tx.Select(&unusedImageUrls, unusedQuery, pq.Array(imageUrls)); err != nil {
+		return nil, err
+	}
+
+	return unusedImageUrls, nil
+}
+
+// GetPostVote gets the user's vote on the post
+func (p *PostRepo) GetPostVote(userId int, postId int) (*PostVote, error) {
+
+	var postVote PostVote
+
+	query := `SELECT voter_id, post_id, vote_value, voted_at 
+	FROM post_votes WHERE voter_id=$1 AND post_id=$2`
+
+	if err := p.db.QueryRowx(query, userId, postId).StructScan(&postVote); err != nil {
+		return nil, err
+	}
+
+	return &postVote, nil
+}
+
+// VotePost sets the user's vote on the post, replacing their earlier vote
+func (p *PostRepo) VotePost(userId int, postId int, voteValue int) (*PostVote, error) {
+
+	var postVote PostVote
+
+	query := `INSERT INTO post_votes(voter_id, post_id, vote_value) VALUES($1,$2,$3)
+	ON CONFLICT(voter_id,post_id) DO UPDATE SET vote_value=EXCLUDED.vote_value, voted_at=NOW()
+	RETURNING voter_id, post_id, vote_value, voted_at`
+
+	if err := p.db.QueryRowx(query, userId, postId, voteValue).StructScan(&postVote); err != nil {
+		return nil, err
+	}
+
+	return &postVote, nil
+}
+
+func (p *PostRepo) RemovePostVote(userId int, postId int) error {
+
+	query := `DELETE FROM post_votes WHERE voter_id=$1 AND post_id=$2`
+
+	if _, err := p.db.Exec(query, userId, postId); err != nil {
 		return err
 	}
 
 	return nil
-
 }
 
 func (p *PostRepo) CheckPostBookmark(userId int, postId int) (bool, error) {
 
 	var postBookmark PostBookmark
 
-	query := `SELECT bookmarked_by_id, bookmarked_post_id, bookmarked_at 
+	query := `SELECT bookmarked_by_id, bookmarked_post_id, bookmarked_at, collection_id 
 	FROM post_bookmarks WHERE bookmarked_by_id=$1 AND bookmarked_post_id=$2`
 
 	if err := p.db.QueryRowx(query, userId, postId).StructScan(&postBookmark); err != nil {
@@ -212,7 +497,7 @@ func (p *PostRepo) CreatePostBookmark(userId int, postId int) (*PostBookmark, er
 
 	var postBookmark PostBookmark
 
-	query := `INSERT INTO post_bookmarks(bookmarked_by_id,bookmarked_post_id) VALUES($1,$2) RETURNING bookmarked_by_id,bookmarked_post_id,bookmarked_at`
+	query := `INSERT INTO post_bookmarks(bookmarked_by_id,bookmarked_post_id) VALUES($1,$2) RETURNING bookmarked_by_id,bookmarked_post_id,bookmarked_at,collection_id`
 
 	if err := p.db.QueryRowx(query, userId, postId).StructScan(&postBookmark); err != nil {
 		return nil, err
@@ -240,183 +525,28 @@ func (p *PostRepo) GetCommunityPosts(communityId int, skip int, limit int, sortB
 	// if search is empty -> fetch all posts without filtering
 	// else filter
 
-	var posts []PostWithMetaData
-	var query string
-	var args []interface{}
-
-	limitParam := 2
-	offsetParam := 3
-
-	whereClause := `WHERE
-      post_community_id = $1
-      AND pc.parent_comment_id IS NULL `
-
-	args = append(args, communityId)
+	whereClause, args := communityPostsFilter(communityId, search)
 
-	if search != "" {
-		whereClause += ` AND post_title ILIKE $2`
-		searchArg := "%" + search + "%"
-		args = append(args, searchArg)
-
-		limitParam = 3
-		offsetParam = 4
-	}
-
-	args = append(args, limit)
-	args = append(args, skip)
-
-	baseQuery := `SELECT
-      p.id,
-      post_title,
-      post_content,
-      post_owner_id,
-      post_community_id,
-      post_created_at,
-      post_updated_at,
-      u.id,
-      email,
-      password,
-      username,
-      is_verified,
-      role,
-      user_image,
-      bio,
-      location,
-      date_of_birth,
-      verified_at,
-      created_at,
-      updated_at,
-      COUNT(DISTINCT (pl.liked_by_id)) AS post_likes_count,
-      COUNT(DISTINCT (pc.id)) AS post_comments_count,
-      COUNT(DISTINCT (pb.bookmarked_by_id)) AS post_bookmarks_count,
-      0.0 as activity_score
-    FROM
-      posts AS p
-      INNER JOIN users AS u ON p.post_owner_id = u.id
-      LEFT JOIN post_likes AS pl ON p.id = pl.liked_post_id
-      LEFT JOIN post_comments AS pc ON p.id = pc.post_id
-      LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
-    `
-
-	if sortBy == SortByNewest {
-
-		query = fmt.Sprintf(`%s %s GROUP BY p.id, u.id ORDER BY post_created_at DESC LIMIT $%d OFFSET $%d`, baseQuery, whereClause, limitParam, offsetParam)
-
-	} else if sortBy == SortByTop {
-
-		query = fmt.Sprintf("%s %s GROUP BY p.id,u.id ORDER BY post_likes_count DESC LIMIT $%d OFFSET $%d", baseQuery, whereClause, limitParam, offsetParam)
-
-	} else if sortBy == SortByRelevance {
-
-		baseQuery := `SELECT
-      p.id,
-      post_title,
-      post_content,
-      post_owner_id,
-      post_community_id,
-      post_created_at,
-      post_updated_at,
-      u.id,
-      email,
-      password,
-      username,
-      is_verified,
-      role,
-      user_image,
-      bio,
-      location,
-      date_of_birth,
-      verified_at,
-      created_at,
-      updated_at,
-      COUNT(DISTINCT (pl.liked_by_id)) AS post_likes_count,
-      COUNT(DISTINCT (pc.id)) AS post_comments_count,
-      COUNT(DISTINCT (pb.bookmarked_by_id)) AS post_bookmarks_count
-    FROM
-      posts AS p
-      INNER JOIN users AS u ON p.post_owner_id = u.id
-      LEFT JOIN post_likes AS pl ON p.id = pl.liked_post_id
-      LEFT JOIN post_comments AS pc ON p.id = pc.post_id
-      LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
-    `
-
-		selectClause := `SELECT
-  *,
-  (
-    (
-      0.3 * post_likes_count + 0.5 * post_comments_count + 0.2 * post_bookmarks_count
-    ) / POWER(
-      (
-        EXTRACT(
-          EPOCH
-          FROM
-            (NOW() - post_created_at)
-        ) / 60
-      ),
-      2
-    )
-  ) AS activity_score`
-
-		query = fmt.Sprintf(`%s FROM (%s %s GROUP BY p.id,u.id) ORDER BY activity_score DESC LIMIT $%d OFFSET $%d`, selectClause, baseQuery, whereClause, limitParam, offsetParam)
-
-	} else {
-		return nil, errors.New("Invalid SortBy")
-	}
-
-	rows, err := p.db.Queryx(query, args...)
+	posts, _, err := p.getPosts(whereClause, args, sortBy, nil, skip, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-
-		var postWithMetaData PostWithMetaData
-		var activityScore float64
-		var postImages []PostImage
-
-		if err := rows.Scan(
-
-			&postWithMetaData.Id, &postWithMetaData.PostTitle, &postWithMetaData.PostContent,
-			&postWithMetaData.PostOwnerId, &postWithMetaData.PostCommunityId, &postWithMetaData.PostCreatedAt,
-			&postWithMetaData.PostUpdatedAt, &postWithMetaData.PostOwner.Id, &postWithMetaData.PostOwner.Email,
-			&postWithMetaData.PostOwner.Password, &postWithMetaData.PostOwner.Username, &postWithMetaData.PostOwner.IsVerified,
-			&postWithMetaData.PostOwner.Role, &postWithMetaData.PostOwner.UserImage, &postWithMetaData.PostOwner.Bio,
-			&postWithMetaData.PostOwner.Location, &postWithMetaData.PostOwner.DateOfBirth, &postWithMetaData.PostOwner.VerifiedAt,
-			&postWithMetaData.PostOwner.CreatedAt, &postWithMetaData.PostOwner.UpdatedAt, &postWithMetaData.PostLikesCount,
-			&postWithMetaData.PostCommentsCount, &postWithMetaData.PostBookmarksCount, &activityScore); err != nil {
-
-			return nil, err
-
-		}
 
-		// a post has many images
-		postId := postWithMetaData.Id
+	return posts, nil
+}
 
-		postImagesQuery := `SELECT id, post_image_url, post_id 
-		FROM post_images WHERE post_id=$1`
+func communityPostsFilter(communityId int, search string) (string, []interface{}) {
 
-		imageRows, err := p.db.Queryx(postImagesQuery, postId)
-		if err != nil {
-			return nil, err
-		}
-
-		for imageRows.Next() {
-			var postImage PostImage
+	args := []interface{}{communityId}
 
-			if err := imageRows.StructScan(&postImage); err != nil {
-				return nil, err
-			}
+	whereClause := `WHERE p.post_community_id = $1`
 
-			postImages = append(postImages, postImage)
-		}
-		imageRows.Close()
-
-		postWithMetaData.PostImages = postImages
-		posts = append(posts, postWithMetaData)
+	if search != "" {
+		args = append(args, search)
+		whereClause += ` AND p.post_search_vector @@ websearch_to_tsquery('english', $2)`
 	}
 
-	return posts, nil
+	return whereClause, args
 }
 
 func (p *PostRepo) GetCommunityPostsCount(communityId int, search string) (int, error) {
@@ -429,9 +559,8 @@ func (p *PostRepo) GetCommunityPostsCount(communityId int, search string) (int,
 	whereClause := `WHERE post_community_id=$1`
 
 	if search != "" {
-		whereClause += ` AND post_title ILIKE $2`
-		searchArg := "%" + search + "%"
-		args = append(args, searchArg)
+		whereClause += ` AND post_search_vector @@ websearch_to_tsquery('english', $2)`
+		args = append(args, search)
 	}
 
 	query := fmt.Sprintf(`SELECT COUNT(*) FROM posts %s`, whereClause)
@@ -447,26 +576,19 @@ func (p *PostRepo) GetCommunityPostsCount(communityId int, search string) (int,
 // get posts from top N user communities with most members
 func (p *PostRepo) GetUserPostsFeed(userId int, n int, skip int, limit int, sortBy SortByStr) ([]PostWithMetaData, error) {
 
-	var posts []PostWithMetaData
-	var query string
+	whereClause, args := userPostsFeedFilter(userId, n)
 
-	baseQuery := `
-      SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
-  p.post_community_id,p.post_created_at,p.post_updated_at,
-  u.id,u.email,u.password,u.username,u.is_verified,u.role,
-  u.user_image,u.bio,u.location,u.date_of_birth,u.verified_at,
-  u.created_at,u.updated_at,
-  COUNT(DISTINCT(pl.liked_by_id)) AS post_likes_count,
-  COUNT(DISTINCT(pc.id)) AS post_comments_count,
-  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count, 
-  0.0 AS activity_score
-      FROM posts 
-AS p INNER JOIN users AS u ON p.post_owner_id=u.id
-LEFT JOIN post_likes AS pl ON p.id = pl.liked_post_id
-LEFT JOIN post_comments AS pc ON p.id = pc.post_id
-LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
-  WHERE post_community_id
-IN (
+	posts, _, err := p.getPosts(whereClause, args, sortBy, nil, skip, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func userPostsFeedFilter(userId int, n int) (string, []interface{}) {
+
+	whereClause := `WHERE p.post_community_id IN (
   SELECT community_id
 FROM (
   SELECT ucl.community_id, COUNT(DISTINCT(ucr.user_id)) AS members_count FROM user_communities AS ucl
@@ -476,69 +598,111 @@ GROUP BY ucl.user_id,ucl.community_id,ucl.joined_at
 ORDER BY members_count DESC
 LIMIT $2 OFFSET 0
   )
-)
-AND pc.parent_comment_id IS NULL
-GROUP BY p.id,u.id`
+)`
 
-	if sortBy == SortByTop {
+	return whereClause, []interface{}{userId, n}
+}
 
-		query = fmt.Sprintf("%s\nORDER BY post_likes_count DESC\nLIMIT $3 OFFSET $4", baseQuery)
+func (p *PostRepo) GetUserPostsFeedCount(userId int, n int) (int, error) {
 
-	} else if sortBy == SortByNewest {
+	var totalCount int
 
-		query = fmt.Sprintf("%s\nORDER BY p.post_created_at DESC\nLIMIT $3 OFFSET $4", baseQuery)
+	query := `SELECT COUNT(*) FROM posts WHERE post_community_id IN (
+		SELECT community_id
+	FROM (
+	  SELECT ucl.community_id, COUNT(DISTINCT(ucr.user_id)) AS members_count FROM user_communities AS ucl
+	INNER JOIN user_communities AS ucr ON ucl.community_id = ucr.community_id
+	WHERE ucl.user_id=$1
+	GROUP BY ucl.user_id,ucl.community_id,ucl.joined_at
+	ORDER BY members_count DESC
+	LIMIT $2 OFFSET 0
+))`
 
-	} else if sortBy == SortByRelevance {
+	if err := p.db.QueryRow(query, userId, n).Scan(&totalCount); err != nil {
+		return -1, err
+	}
 
-		baseQuery = `SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
-  p.post_community_id,p.post_created_at,p.post_updated_at,
-  u.id,u.email,u.password,u.username,u.is_verified,u.role,
-  u.user_image,u.bio,u.location,u.date_of_birth,u.verified_at,
-  u.created_at,u.updated_at,
-  COUNT(DISTINCT(pl.liked_by_id)) AS post_likes_count,
-  COUNT(DISTINCT(pc.id)) AS post_comments_count,
-  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count FROM posts 
-AS p INNER JOIN users AS u ON p.post_owner_id=u.id
-LEFT JOIN post_likes AS pl ON p.id = pl.liked_post_id
-LEFT JOIN post_comments AS pc ON p.id = pc.post_id
-LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
-  WHERE post_community_id
-IN (
+	return totalCount, nil
+}
+
+// private communities are left out of the explore feed
+func (p *PostRepo) GetPostsFeed(n int, skip int, limit int, sortBy SortByStr) ([]PostWithMetaData, error) {
+
+	whereClause, args := postsFeedFilter(n)
+
+	posts, _, err := p.getPosts(whereClause, args, sortBy, nil, skip, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func postsFeedFilter(n int) (string, []interface{}) {
+
+	whereClause := `WHERE p.post_community_id IN (
   SELECT community_id
-FROM (
-  SELECT ucl.community_id, COUNT(DISTINCT(ucr.user_id)) AS members_count FROM user_communities AS ucl
-INNER JOIN user_communities AS ucr ON ucl.community_id = ucr.community_id
-WHERE ucl.user_id=$1
-GROUP BY ucl.user_id,ucl.community_id,ucl.joined_at
-ORDER BY members_count DESC
-LIMIT $2 OFFSET 0
+  FROM (
+    SELECT uc.community_id, COUNT(DISTINCT(uc.user_id)) AS members_count
+    FROM user_communities AS uc INNER JOIN communities AS c ON uc.community_id = c.id
+    WHERE c.community_visibility <> 'private'
+    GROUP BY uc.community_id
+    ORDER BY members_count DESC
+    LIMIT $1 OFFSET 0
   )
-)
-AND pc.parent_comment_id IS NULL
-GROUP BY p.id,u.id`
+)`
+
+	return whereClause, []interface{}{n}
+}
 
-		selectClause := `SELECT *,(
-    (
-      0.3 * post_likes_count + 0.5 * post_comments_count + 0.2 * post_bookmarks_count
-    ) / POWER(
-      (
-        EXTRACT(
-          EPOCH
-          FROM
-            (NOW() - post_created_at)
-        ) / 60
-      ),
-      2
-    )
-  ) AS activity_score`
+// GetExplorePostsFeedCount gets total count of posts from top N communities by member count
+func (p *PostRepo) GetPostsFeedCount(n int) (int, error) {
 
-		query = fmt.Sprintf("%s\n FROM (%s) ORDER BY activity_score DESC\n LIMIT $3 OFFSET $4", selectClause, baseQuery)
+	var totalCount int
+
+	query := `SELECT COUNT(*) FROM posts WHERE post_community_id IN (
+		SELECT community_id
+		FROM (
+			SELECT uc.community_id, COUNT(DISTINCT(uc.user_id)) AS members_count 
+			FROM user_communities AS uc INNER JOIN communities AS c ON uc.community_id = c.id
+			WHERE c.community_visibility <> 'private'
+			GROUP BY uc.community_id
+			ORDER BY members_count DESC
+			LIMIT $1 OFFSET 0
+		)
+	)`
 
-	} else {
-		return nil, errors.New("invalid sortBy")
+	if err := p.db.QueryRow(query, n).Scan(&totalCount); err != nil {
+		return -1, err
 	}
 
-	rows, err := p.db.Queryx(query, userId, n, limit, skip) // n is the top n communities being selected according to members count
+	return totalCount, nil
+}
+
+// scan destinations for the post and post owner columns shared by every PostWithMetaData query,
+// followed by the vote, comments and bookmarks counts
+func postWithMetaDataScanDest(post *PostWithMetaData) []interface{} {
+	return []interface{}{
+		&post.Id, &post.PostTitle, &post.PostContent,
+		&post.PostOwnerId, &post.PostCommunityId, &post.PostCreatedAt,
+		&post.PostUpdatedAt, &post.PostOwner.Id, &post.PostOwner.Email,
+		&post.PostOwner.Password, &post.PostOwner.Username, &post.PostOwner.IsVerified,
+		&post.PostOwner.Role, &post.PostOwner.UserImage, &post.PostOwner.Bio,
+		&post.PostOwner.Location, &post.PostOwner.DateOfBirth, &post.PostOwner.VerifiedAt,
+		&post.PostOwner.CreatedAt, &post.PostOwner.UpdatedAt, &post.PostUpvotesCount,
+		&post.PostDownvotesCount, &post.PostScore, &post.PostUpvoteRatio,
+		&post.PostCommentsCount, &post.PostBookmarksCount,
+	}
+}
+
+func (p *PostRepo) getPostImages(postId int) ([]PostImage, error) {
+
+	var postImages []PostImage
+
+	query := `SELECT id, post_image_url, post_id 
+	FROM post_images WHERE post_id=$1`
+
+	rows, err := p.db.Queryx(query, postId)
 	if err != nil {
 		return nil, err
 	}
@@ -546,40 +710,62 @@ GROUP BY p.id,u.id`
 
 	for rows.Next() {
 
-		var postWithMetaData PostWithMetaData
-		var activityScore float64
-		var postImages []PostImage
-
-		if err := rows.Scan(
-			&postWithMetaData.Id, &postWithMetaData.PostTitle, &postWithMetaData.PostContent,
-			&postWithMetaData.PostOwnerId, &postWithMetaData.PostCommunityId, &postWithMetaData.PostCreatedAt,
-			&postWithMetaData.PostUpdatedAt, &postWithMetaData.PostOwner.Id, &postWithMetaData.PostOwner.Email,
-			&postWithMetaData.PostOwner.Password, &postWithMetaData.PostOwner.Username, &postWithMetaData.PostOwner.IsVerified,
-			&postWithMetaData.PostOwner.Role, &postWithMetaData.PostOwner.UserImage, &postWithMetaData.PostOwner.Bio,
-			&postWithMetaData.PostOwner.Location, &postWithMetaData.PostOwner.DateOfBirth, &postWithMetaData.PostOwner.VerifiedAt,
-			&postWithMetaData.PostOwner.CreatedAt, &postWithMetaData.PostOwner.UpdatedAt, &postWithMetaData.PostLikesCount,
-			&postWithMetaData.PostCommentsCount, &postWithMetaData.PostBookmarksCount, &activityScore); err != nil {
+		var postImage PostImage
+
+		if err := rows.StructScan(&postImage); err != nil {
 			return nil, err
 		}
 
-		imagesQuery := `SELECT id, post_image_url, post_id 
-		FROM post_images WHERE post_id=$1`
+		postImages = append(postImages, postImage)
+	}
 
-		imageRows, err := p.db.Queryx(imagesQuery, postWithMetaData.Id)
-		if err != nil {
-			return nil, err
-		}
+	return postImages, nil
+}
 
-		for imageRows.Next() {
-			var postImage PostImage
+// GetUserPosts gets posts created by a user, most recent first, leaving out posts in private communities
+func (p *PostRepo) GetUserPosts(userId int, skip int, limit int) ([]PostWithMetaData, error) {
 
-			if err := imageRows.StructScan(&postImage); err != nil {
-				return nil, err
-			}
+	var posts []PostWithMetaData
 
-			postImages = append(postImages, postImage)
+	query := `SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
+  p.post_community_id,p.post_created_at,p.post_updated_at,
+  u.id,u.email,u.password,u.username,u.is_verified,u.role,
+  u.user_image,u.bio,u.location,u.date_of_birth,u.verified_at,
+  u.created_at,u.updated_at,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) AS post_upvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_downvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) - COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_score,
+  COALESCE(COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(pv.voter_id)), 0), 0) AS post_upvote_ratio,
+  COUNT(DISTINCT(pc.id)) AS post_comments_count,
+  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count
+FROM posts AS p INNER JOIN users AS u ON p.post_owner_id=u.id
+LEFT JOIN post_votes AS pv ON p.id = pv.post_id
+LEFT JOIN post_comments AS pc ON p.id = pc.post_id
+LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
+WHERE p.post_owner_id=$1 AND pc.parent_comment_id IS NULL
+AND p.post_community_id NOT IN (SELECT id FROM communities WHERE community_visibility='private')
+GROUP BY p.id,u.id
+ORDER BY p.post_created_at DESC
+LIMIT $2 OFFSET $3`
+
+	rows, err := p.db.Queryx(query, userId, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var postWithMetaData PostWithMetaData
+
+		if err := rows.Scan(postWithMetaDataScanDest(&postWithMetaData)...); err != nil {
+			return nil, err
+		}
+
+		postImages, err := p.getPostImages(postWithMetaData.Id)
+		if err != nil {
+			return nil, err
 		}
-		imageRows.Close()
 
 		postWithMetaData.PostImages = postImages
 		posts = append(posts, postWithMetaData)
@@ -588,118 +774,138 @@ GROUP BY p.id,u.id`
 	return posts, nil
 }
 
-func (p *PostRepo) GetUserPostsFeedCount(userId int, n int) (int, error) {
+func (p *PostRepo) GetUserPostsCount(userId int) (int, error) {
 
 	var totalCount int
 
-	query := `SELECT COUNT(*) FROM posts WHERE post_community_id IN (
-		SELECT community_id
-	FROM (
-	  SELECT ucl.community_id, COUNT(DISTINCT(ucr.user_id)) AS members_count FROM user_communities AS ucl
-	INNER JOIN user_communities AS ucr ON ucl.community_id = ucr.community_id
-	WHERE ucl.user_id=$1
-	GROUP BY ucl.user_id,ucl.community_id,ucl.joined_at
-	ORDER BY members_count DESC
-	LIMIT $2 OFFSET 0
-))`
+	query := `SELECT COUNT(*) FROM posts WHERE post_owner_id=$1
+	AND post_community_id NOT IN (SELECT id FROM communities WHERE community_visibility='private')`
 
-	if err := p.db.QueryRow(query, userId, n).Scan(&totalCount); err != nil {
+	if err := p.db.QueryRow(query, userId).Scan(&totalCount); err != nil {
 		return -1, err
 	}
 
 	return totalCount, nil
 }
 
-func (p *PostRepo) GetPostsFeed(n int, skip int, limit int, sortBy SortByStr) ([]PostWithMetaData, error) {
+// GetUserBookmarkedPosts gets posts bookmarked by a user, most recently bookmarked first
+func (p *PostRepo) GetUserBookmarkedPosts(userId int, collectionId *int, skip int, limit int) ([]PostWithMetaData, error) {
 
 	var posts []PostWithMetaData
-	var query string
+	var args []interface{}
+
+	limitParam := 2
+	offsetParam := 3
 
-	baseQuery := `
-      SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
+	whereClause := `WHERE ux.bookmarked_by_id=$1 AND pc.parent_comment_id IS NULL`
+	args = append(args, userId)
+
+	// bookmarks can optionally be narrowed down to a single collection
+	if collectionId != nil {
+		whereClause += ` AND ux.collection_id=$2`
+		args = append(args, *collectionId)
+
+		limitParam = 3
+		offsetParam = 4
+	}
+
+	args = append(args, limit)
+	args = append(args, skip)
+
+	query := fmt.Sprintf(`SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
   p.post_community_id,p.post_created_at,p.post_updated_at,
   u.id,u.email,u.password,u.username,u.is_verified,u.role,
   u.user_image,u.bio,u.location,u.date_of_birth,u.verified_at,
   u.created_at,u.updated_at,
-  COUNT(DISTINCT(pl.liked_by_id)) AS post_likes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) AS post_upvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_downvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) - COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_score,
+  COALESCE(COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(pv.voter_id)), 0), 0) AS post_upvote_ratio,
   COUNT(DISTINCT(pc.id)) AS post_comments_count,
-  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count, 
-  0.0 AS activity_score
-      FROM posts 
-AS p INNER JOIN users AS u ON p.post_owner_id=u.id
-LEFT JOIN post_likes AS pl ON p.id = pl.liked_post_id
+  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count
+FROM post_bookmarks AS ux INNER JOIN posts AS p ON ux.bookmarked_post_id=p.id
+INNER JOIN users AS u ON p.post_owner_id=u.id
+LEFT JOIN post_votes AS pv ON p.id = pv.post_id
 LEFT JOIN post_comments AS pc ON p.id = pc.post_id
 LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
-  WHERE post_community_id IN (
-  SELECT community_id
-  FROM (
-    SELECT uc.community_id, COUNT(DISTINCT(uc.user_id)) AS members_count 
-    FROM user_communities AS uc
-    GROUP BY uc.community_id
-    ORDER BY members_count DESC
-    LIMIT $1 OFFSET 0
-  )
-)
-AND pc.parent_comment_id IS NULL
-GROUP BY p.id,u.id`
+%s
+GROUP BY p.id,u.id,ux.bookmarked_at
+ORDER BY ux.bookmarked_at DESC
+LIMIT $%d OFFSET $%d`, whereClause, limitParam, offsetParam)
 
-	if sortBy == SortByTop {
+	rows, err := p.db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		query = fmt.Sprintf("%s\nORDER BY post_likes_count DESC\nLIMIT $2 OFFSET $3", baseQuery)
+	for rows.Next() {
 
-	} else if sortBy == SortByNewest {
+		var postWithMetaData PostWithMetaData
 
-		query = fmt.Sprintf("%s\nORDER BY p.post_created_at DESC\nLIMIT $2 OFFSET $3", baseQuery)
+		if err := rows.Scan(postWithMetaDataScanDest(&postWithMetaData)...); err != nil {
+			return nil, err
+		}
 
-	} else if sortBy == SortByRelevance {
+		postImages, err := p.getPostImages(postWithMetaData.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		postWithMetaData.PostImages = postImages
+		posts = append(posts, postWithMetaData)
+	}
+
+	return posts, nil
+}
+
+func (p *PostRepo) GetUserBookmarkedPostsCount(userId int, collectionId *int) (int, error) {
+
+	var totalCount int
+	var args []interface{}
+
+	query := `SELECT COUNT(*) FROM post_bookmarks AS ux WHERE ux.bookmarked_by_id=$1`
+	args = append(args, userId)
 
-		baseQuery = `SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
+	if collectionId != nil {
+		query += ` AND ux.collection_id=$2`
+		args = append(args, *collectionId)
+	}
+
+	if err := p.db.QueryRow(query, args...).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+// GetUserLikedPosts gets posts liked by a user, most recently liked first
+func (p *PostRepo) GetUserLikedPosts(userId int, skip int, limit int) ([]PostWithMetaData, error) {
+
+	var posts []PostWithMetaData
+
+	query := `SELECT p.id,p.post_title,p.post_content,p.post_owner_id,
   p.post_community_id,p.post_created_at,p.post_updated_at,
   u.id,u.email,u.password,u.username,u.is_verified,u.role,
   u.user_image,u.bio,u.location,u.date_of_birth,u.verified_at,
   u.created_at,u.updated_at,
-  COUNT(DISTINCT(pl.liked_by_id)) AS post_likes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) AS post_upvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_downvotes_count,
+  COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1) - COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = -1) AS post_score,
+  COALESCE(COUNT(DISTINCT(pv.voter_id)) FILTER (WHERE pv.vote_value = 1)::float / NULLIF(COUNT(DISTINCT(pv.voter_id)), 0), 0) AS post_upvote_ratio,
   COUNT(DISTINCT(pc.id)) AS post_comments_count,
-  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count FROM posts 
-AS p INNER JOIN users AS u ON p.post_owner_id=u.id
-LEFT JOIN post_likes AS pl ON p.id = pl.liked_post_id
+  COUNT(DISTINCT(pb.bookmarked_by_id)) AS post_bookmarks_count
+FROM post_votes AS ux INNER JOIN posts AS p ON ux.post_id=p.id
+INNER JOIN users AS u ON p.post_owner_id=u.id
+LEFT JOIN post_votes AS pv ON p.id = pv.post_id
 LEFT JOIN post_comments AS pc ON p.id = pc.post_id
 LEFT JOIN post_bookmarks AS pb ON p.id = pb.bookmarked_post_id
-  WHERE post_community_id IN (
-  SELECT community_id
-  FROM (
-    SELECT uc.community_id, COUNT(DISTINCT(uc.user_id)) AS members_count 
-    FROM user_communities AS uc
-    GROUP BY uc.community_id
-    ORDER BY members_count DESC
-    LIMIT $1 OFFSET 0
-  )
-)
-AND pc.parent_comment_id IS NULL
-GROUP BY p.id,u.id`
-
-		selectClause := `SELECT *,(
-    (
-      0.3 * post_likes_count + 0.5 * post_comments_count + 0.2 * post_bookmarks_count
-    ) / POWER(
-      (
-        EXTRACT(
-          EPOCH
-          FROM
-            (NOW() - post_created_at)
-        ) / 60
-      ),
-      2
-    )
-  ) AS activity_score `
+WHERE ux.voter_id=$1 AND ux.vote_value = 1 AND pc.parent_comment_id IS NULL
+GROUP BY p.id,u.id,ux.voted_at
+ORDER BY ux.voted_at DESC
+LIMIT $2 OFFSET $3`
 
-		query = fmt.Sprintf("%s\n FROM (%s) ORDER BY activity_score DESC\n LIMIT $2 OFFSET $3", selectClause, baseQuery)
-
-	} else {
-		return nil, errors.New("invalid sortBy")
-	}
-
-	rows, err := p.db.Queryx(query, n, limit, skip) // n is the top n communities being selected according to members count
+	rows, err := p.db.Queryx(query, userId, limit, skip)
 	if err != nil {
 		return nil, err
 	}
@@ -708,38 +914,16 @@ GROUP BY p.id,u.id`
 	for rows.Next() {
 
 		var postWithMetaData PostWithMetaData
-		var activityScore float64
-		var postImages []PostImage
-
-		if err := rows.Scan(
-			&postWithMetaData.Id, &postWithMetaData.PostTitle, &postWithMetaData.PostContent,
-			&postWithMetaData.PostOwnerId, &postWithMetaData.PostCommunityId, &postWithMetaData.PostCreatedAt,
-			&postWithMetaData.PostUpdatedAt, &postWithMetaData.PostOwner.Id, &postWithMetaData.PostOwner.Email,
-			&postWithMetaData.PostOwner.Password, &postWithMetaData.PostOwner.Username, &postWithMetaData.PostOwner.IsVerified,
-			&postWithMetaData.PostOwner.Role, &postWithMetaData.PostOwner.UserImage, &postWithMetaData.PostOwner.Bio,
-			&postWithMetaData.PostOwner.Location, &postWithMetaData.PostOwner.DateOfBirth, &postWithMetaData.PostOwner.VerifiedAt,
-			&postWithMetaData.PostOwner.CreatedAt, &postWithMetaData.PostOwner.UpdatedAt, &postWithMetaData.PostLikesCount,
-			&postWithMetaData.PostCommentsCount, &postWithMetaData.PostBookmarksCount, &activityScore); err != nil {
+
+		if err := rows.Scan(postWithMetaDataScanDest(&postWithMetaData)...); err != nil {
 			return nil, err
 		}
 
-		imagesQuery := `SELECT id, post_image_url, post_id 
-		FROM post_images WHERE post_id=$1`
-
-		imageRows, err := p.db.Queryx(imagesQuery, postWithMetaData.Id)
+		postImages, err := p.getPostImages(postWithMetaData.Id)
 		if err != nil {
 			return nil, err
 		}
 
-		for imageRows.Next() {
-			var postImage PostImage
-			if err := imageRows.StructScan(&postImage); err != nil {
-				return nil, err
-			}
-			postImages = append(postImages, postImage)
-		}
-		imageRows.Close()
-
 		postWithMetaData.PostImages = postImages
 		posts = append(posts, postWithMetaData)
 	}
@@ -747,23 +931,13 @@ GROUP BY p.id,u.id`
 	return posts, nil
 }
 
-// GetExplorePostsFeedCount gets total count of posts from top N communities by member count
-func (p *PostRepo) GetPostsFeedCount(n int) (int, error) {
+func (p *PostRepo) GetUserLikedPostsCount(userId int) (int, error) {
 
 	var totalCount int
 
-	query := `SELECT COUNT(*) FROM posts WHERE post_community_id IN (
-		SELECT community_id
-		FROM (
-			SELECT uc.community_id, COUNT(DISTINCT(uc.user_id)) AS members_count 
-			FROM user_communities AS uc
-			GROUP BY uc.community_id
-			ORDER BY members_count DESC
-			LIMIT $1 OFFSET 0
-		)
-	)`
+	query := `SELECT COUNT(*) FROM post_votes AS ux WHERE ux.voter_id=$1 AND ux.vote_value = 1`
 
-	if err := p.db.QueryRow(query, n).Scan(&totalCount); err != nil {
+	if err := p.db.QueryRow(query, userId).Scan(&totalCount); err != nil {
 		return -1, err
 	}
 
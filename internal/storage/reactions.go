@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// reactions allowed in communities that haven't configured their own
+var DefaultAllowedReactions = []string{"👍", "😂", "🎉", "❤️"}
+
+type PostReaction struct {
+	UserId    int    `db:"user_id" json:"user_id"`
+	PostId    int    `db:"post_id" json:"post_id"`
+	Reaction  string `db:"reaction" json:"reaction"`
+	ReactedAt string `db:"reacted_at" json:"reacted_at"`
+}
+
+type PostCommentReaction struct {
+	UserId    int    `db:"user_id" json:"user_id"`
+	CommentId int    `db:"comment_id" json:"comment_id"`
+	Reaction  string `db:"reaction" json:"reaction"`
+	ReactedAt string `db:"reacted_at" json:"reacted_at"`
+}
+
+type ReactionRepo struct {
+	db *sqlx.DB
+}
+
+func NewReactionRepo(db *sqlx.DB) *ReactionRepo {
+	return &ReactionRepo{
+		db: db,
+	}
+}
+
+// GetCommunityAllowedReactions gets the reactions allowed in the community, the default set when it hasn't configured any
+func (rr *ReactionRepo) GetCommunityAllowedReactions(communityId int) ([]string, error) {
+
+	var allowedReactions pq.StringArray
+
+	query := `SELECT allowed_reactions FROM communities WHERE id=$1`
+
+	if err := rr.db.QueryRow(query, communityId).Scan(&allowedReactions); err != nil {
+		return nil, err
+	}
+
+	if allowedReactions == nil {
+		return DefaultAllowedReactions, nil
+	}
+
+	return allowedReactions, nil
+}
+
+// UpdateCommunityAllowedReactions replaces the reactions allowed in the community, an empty set turns reactions off
+// and a nil set goes back to the default one. reactions already given stay
+func (rr *ReactionRepo) UpdateCommunityAllowedReactions(communityId int, allowedReactions []string) ([]string, error) {
+
+	var updatedReactions pq.StringArray
+
+	query := `UPDATE communities SET allowed_reactions=$1 WHERE id=$2 RETURNING allowed_reactions`
+
+	if err := rr.db.QueryRow(query, pq.StringArray(allowedReactions), communityId).Scan(&updatedReactions); err != nil {
+		return nil, err
+	}
+
+	if updatedReactions == nil {
+		return DefaultAllowedReactions, nil
+	}
+
+	return updatedReactions, nil
+}
+
+func (rr *ReactionRepo) CheckPostReaction(userId int, postId int, reaction string) (bool, error) {
+
+	var postReaction PostReaction
+
+	query := `SELECT user_id, post_id, reaction, reacted_at
+	FROM post_reactions WHERE user_id=$1 AND post_id=$2 AND reaction=$3`
+
+	if err := rr.db.QueryRowx(query, userId, postId, reaction).StructScan(&postReaction); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (rr *ReactionRepo) CreatePostReaction(userId int, postId int, reaction string) (*PostReaction, error) {
+
+	var postReaction PostReaction
+
+	query := `INSERT INTO post_reactions(user_id, post_id, reaction) VALUES($1,$2,$3) RETURNING user_id, post_id, reaction, reacted_at`
+
+	if err := rr.db.QueryRowx(query, userId, postId, reaction).StructScan(&postReaction); err != nil {
+		return nil, err
+	}
+
+	return &postReaction, nil
+}
+
+func (rr *ReactionRepo) RemovePostReaction(userId int, postId int, reaction string) error {
+
+	query := `DELETE FROM post_reactions WHERE user_id=$1 AND post_id=$2 AND reaction=$3`
+
+	if _, err := rr.db.Exec(query, userId, postId, reaction); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (rr *ReactionRepo) CheckCommentReaction(userId int, commentId int, reaction string) (bool, error) {
+
+	var commentReaction PostCommentReaction
+
+	query := `SELECT user_id, comment_id, reaction, reacted_at
+	FROM post_comment_reactions WHERE user_id=$1 AND comment_id=$2 AND reaction=$3`
+
+	if err := rr.db.QueryRowx(query, userId, commentId, reaction).StructScan(&commentReaction); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (rr *ReactionRepo) CreateCommentReaction(userId int, commentId int, reaction string) (*PostCommentReaction, error) {
+
+	var commentReaction PostCommentReaction
+
+	query := `INSERT INTO post_comment_reactions(user_id, comment_id, reaction) VALUES($1,$2,$3) RETURNING user_id, comment_id, reaction, reacted_at`
+
+	if err := rr.db.QueryRowx(query, userId, commentId, reaction).StructScan(&commentReaction); err != nil {
+		return nil, err
+	}
+
+	return &commentReaction, nil
+}
+
+func (rr *ReactionRepo) RemoveCommentReaction(userId int, commentId int, reaction string) error {
+
+	query := `DELETE FROM post_comment_reactions WHERE user_id=$1 AND comment_id=$2 AND reaction=$3`
+
+	if _, err := rr.db.Exec(query, userId, commentId, reaction); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type reactionCount struct {
+	TargetId int    `db:"target_id"`
+	Reaction string `db:"reaction"`
+	Count    int    `db:"count"`
+}
+
+type viewerReaction struct {
+	TargetId int    `db:"target_id"`
+	Reaction string `db:"reaction"`
+}
+
+// getReactions gets the reaction counts of the targets in table (keyed by targetColumn) along with the
+// reactions given by userId, a zero userId skips the viewer's reactions
+func (rr *ReactionRepo) getReactions(table string, targetColumn string, userId int, targetIds []int) (map[int]map[string]int, map[int][]string, error) {
+
+	var counts []reactionCount
+	var viewerReactions []viewerReaction
+
+	ids := make([]int64, len(targetIds))
+	for i, id := range targetIds {
+		ids[i] = int64(id)
+	}
+
+	countsQuery := `SELECT ` + targetColumn + ` AS target_id, reaction, COUNT(*) AS count FROM ` + table + `
+	WHERE ` + targetColumn + ` = ANY($1) GROUP BY ` + targetColumn + `, reaction`
+
+	if err := rr.db.Select(&counts, countsQuery, pq.Array(ids)); err != nil {
+		return nil, nil, err
+	}
+
+	reactionCounts := make(map[int]map[string]int)
+	for _, count := range counts {
+		if reactionCounts[count.TargetId] == nil {
+			reactionCounts[count.TargetId] = make(map[string]int)
+		}
+		reactionCounts[count.TargetId][count.Reaction] = count.Count
+	}
+
+	userReactions := make(map[int][]string)
+	if userId == 0 {
+		return reactionCounts, userReactions, nil
+	}
+
+	viewerQuery := `SELECT ` + targetColumn + ` AS target_id, reaction FROM ` + table + `
+	WHERE user_id=$1 AND ` + targetColumn + ` = ANY($2) ORDER BY reacted_at`
+
+	if err := rr.db.Select(&viewerReactions, viewerQuery, userId, pq.Array(ids)); err != nil {
+		return nil, nil, err
+	}
+
+	for _, reaction := range viewerReactions {
+		userReactions[reaction.TargetId] = append(userReactions[reaction.TargetId], reaction.Reaction)
+	}
+
+	return reactionCounts, userReactions, nil
+}
+
+// SetPostsReactions sets the reaction counts of each post and the reactions userId gave it, a zero userId
+// is an anonymous viewer
+func (rr *ReactionRepo) SetPostsReactions(userId int, posts []PostWithMetaData) error {
+
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIds := make([]int, len(posts))
+	for i, post := range posts {
+		postIds[i] = post.Id
+	}
+
+	reactionCounts, userReactions, err := rr.getReactions("post_reactions", "post_id", userId, postIds)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+
+		posts[i].Reactions = reactionCounts[posts[i].Id]
+		if posts[i].Reactions == nil {
+			posts[i].Reactions = map[string]int{}
+		}
+
+		posts[i].ViewerReactions = userReactions[posts[i].Id]
+		if posts[i].ViewerReactions == nil {
+			posts[i].ViewerReactions = []string{}
+		}
+	}
+
+	return nil
+}
+
+// SetCommentsReactions sets the reaction counts of each comment and the reactions userId gave it, a zero
+// userId is an anonymous viewer. comments are pointers so nested comment trees can be passed flattened
+func (rr *ReactionRepo) SetCommentsReactions(userId int, comments []*PostCommentWithMetaData) error {
+
+	if len(comments) == 0 {
+		return nil
+	}
+
+	commentIds := make([]int, len(comments))
+	for i, comment := range comments {
+		commentIds[i] = comment.Id
+	}
+
+	reactionCounts, userReactions, err := rr.getReactions("post_comment_reactions", "comment_id", userId, commentIds)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+
+		comment.Reactions = reactionCounts[comment.Id]
+		if comment.Reactions == nil {
+			comment.Reactions = map[string]int{}
+		}
+
+		comment.ViewerReactions = userReactions[comment.Id]
+		if comment.ViewerReactions == nil {
+			comment.ViewerReactions = []string{}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// a refresh token family is every token descended from a single login,
+// the family id is the id of the session the login created
+type RefreshToken struct {
+	Token      string  `db:"token" json:"-"`
+	UserId     int     `db:"user_id" json:"user_id"`
+	FamilyId   string  `db:"family_id" json:"family_id"`
+	Expiration string  `db:"expiration" json:"expiration"`
+	RotatedAt  *string `db:"rotated_at" json:"rotated_at"`
+	RevokedAt  *string `db:"revoked_at" json:"revoked_at"`
+	CreatedAt  string  `db:"created_at" json:"created_at"`
+}
+
+type RefreshTokenRepo struct {
+	db *sqlx.DB
+}
+
+func NewRefreshTokenRepo(db *sqlx.DB) *RefreshTokenRepo {
+	return &RefreshTokenRepo{db: db}
+}
+
+func (t *RefreshTokenRepo) CreateRefreshToken(hashedToken string, userId int, familyId string, expiration time.Time) (*RefreshToken, error) {
+
+	var refreshToken RefreshToken
+
+	query := `INSERT INTO refresh_tokens(token, user_id, family_id, expiration) VALUES($1,$2,$3,$4) RETURNING
+	token, user_id, family_id, expiration, rotated_at, revoked_at, created_at`
+
+	if err := t.db.QueryRowx(query, hashedToken, userId, familyId, expiration).StructScan(&refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &refreshToken, nil
+}
+
+func (t *RefreshTokenRepo) GetRefreshToken(hashedToken string) (*RefreshToken, error) {
+
+	var refreshToken RefreshToken
+
+	query := `SELECT token, user_id, family_id, expiration, rotated_at, revoked_at, created_at
+	FROM refresh_tokens WHERE token=$1`
+
+	if err := t.db.QueryRowx(query, hashedToken).StructScan(&refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &refreshToken, nil
+}
+
+// RotateRefreshToken marks the current token as rotated and issues its successor in the same family.
+// Returns sql.ErrNoRows if the token was already rotated or revoked, e.g. by a concurrent request.
+func (t *RefreshTokenRepo) RotateRefreshToken(hashedToken string, newHashedToken string, expiration time.Time) (*RefreshToken, error) {
+
+	var currentToken RefreshToken
+	var newToken RefreshToken
+
+	tx, err := t.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	rotateQuery := `UPDATE refresh_tokens SET rotated_at=NOW()
+	WHERE token=$1 AND rotated_at IS NULL AND revoked_at IS NULL AND expiration > $2 RETURNING
+	token, user_id, family_id, expiration, rotated_at, revoked_at, created_at`
+
+	if err := tx.QueryRowx(rotateQuery, hashedToken, time.Now()).StructScan(&currentToken); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	createQuery := `INSERT INTO refresh_tokens(token, user_id, family_id, expiration) VALUES($1,$2,$3,$4) RETURNING
+	token, user_id, family_id, expiration, rotated_at, revoked_at, created_at`
+
+	if err := tx.QueryRowx(createQuery, newHashedToken, currentToken.UserId, currentToken.FamilyId, expiration).StructScan(&newToken); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &newToken, nil
+}
+
+func (t *RefreshTokenRepo) RevokeRefreshTokenFamily(familyId string) error {
+
+	query := `UPDATE refresh_tokens SET revoked_at=NOW() WHERE family_id=$1 AND revoked_at IS NULL`
+
+	_, err := t.db.Exec(query, familyId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *RefreshTokenRepo) RevokeUserRefreshTokens(userId int) error {
+
+	query := `UPDATE refresh_tokens SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL`
+
+	_, err := t.db.Exec(query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
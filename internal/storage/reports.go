@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "spam"
+	ReportReasonHarassment     ReportReason = "harassment"
+	ReportReasonHateSpeech     ReportReason = "hate_speech"
+	ReportReasonViolence       ReportReason = "violence"
+	ReportReasonSexualContent  ReportReason = "sexual_content"
+	ReportReasonMisinformation ReportReason = "misinformation"
+	ReportReasonOther          ReportReason = "other"
+)
+
+type ReportStatus string
+
+const (
+	ReportStatusOpen     ReportStatus = "open"
+	ReportStatusResolved ReportStatus = "resolved"
+)
+
+type ReportResolution string
+
+const (
+	ReportResolutionContentRemoved ReportResolution = "content_removed"
+	ReportResolutionDismissed      ReportResolution = "dismissed"
+	ReportResolutionAuthorBanned   ReportResolution = "author_banned"
+)
+
+// a report flags either a post or a comment, exactly one of PostId and CommentId is set
+type Report struct {
+	Id               int               `db:"id" json:"id"`
+	ReporterId       int               `db:"reporter_id" json:"reporter_id"`
+	CommunityId      int               `db:"community_id" json:"community_id"`
+	PostId           *int              `db:"post_id" json:"post_id"`
+	CommentId        *int              `db:"comment_id" json:"comment_id"`
+	ReportReason     ReportReason      `db:"report_reason" json:"report_reason"`
+	ReportDetails    string            `db:"report_details" json:"report_details"`
+	ReportStatus     ReportStatus      `db:"report_status" json:"report_status"`
+	ReportResolution *ReportResolution `db:"report_resolution" json:"report_resolution"`
+	ResolvedById     *int              `db:"resolved_by_id" json:"resolved_by_id"`
+	ResolvedAt       *string           `db:"resolved_at" json:"resolved_at"`
+	CreatedAt        string            `db:"created_at" json:"created_at"`
+}
+
+type ReportRepo struct {
+	db *sqlx.DB
+}
+
+func NewReportRepo(db *sqlx.DB) *ReportRepo {
+	return &ReportRepo{
+		db: db,
+	}
+}
+
+const reportColumns = `id, reporter_id, community_id, post_id, comment_id, report_reason, report_details,
+	report_status, report_resolution, resolved_by_id, resolved_at, created_at`
+
+// reports whose content was deleted outside of moderation are left out of the queue
+const reportTargetExistsClause = `(post_id IS NOT NULL OR comment_id IS NOT NULL)`
+
+func (r *ReportRepo) CreateReport(reporterId int, communityId int, postId *int, commentId *int, reportReason ReportReason, reportDetails string) (*Report, error) {
+
+	var report Report
+
+	query := fmt.Sprintf(`INSERT INTO reports(reporter_id, community_id, post_id, comment_id, report_reason, report_details)
+	VALUES($1,$2,$3,$4,$5,$6) RETURNING %s`, reportColumns)
+
+	if err := r.db.QueryRowx(query, reporterId, communityId, postId, commentId, reportReason, reportDetails).StructScan(&report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// CheckOpenPostReport reports whether the user already has an open report on the post
+func (r *ReportRepo) CheckOpenPostReport(reporterId int, postId int) (bool, error) {
+
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM reports WHERE reporter_id=$1 AND post_id=$2 AND report_status='open')`
+
+	if err := r.db.QueryRow(query, reporterId, postId).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *ReportRepo) CheckOpenCommentReport(reporterId int, commentId int) (bool, error) {
+
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM reports WHERE reporter_id=$1 AND comment_id=$2 AND report_status='open')`
+
+	if err := r.db.QueryRow(query, reporterId, commentId).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *ReportRepo) GetReportById(id int) (*Report, error) {
+
+	var report Report
+
+	query := fmt.Sprintf(`SELECT %s FROM reports WHERE id=$1`, reportColumns)
+
+	if err := r.db.QueryRowx(query, id).StructScan(&report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// GetCommunityReports gets the reports of a community with the given status, oldest first
+func (r *ReportRepo) GetCommunityReports(communityId int, status ReportStatus, offset int, limit int) ([]Report, error) {
+
+	query := fmt.Sprintf(`SELECT %s FROM reports WHERE community_id=$1 AND report_status=$2 AND %s
+	ORDER BY created_at ASC LIMIT $3 OFFSET $4`, reportColumns, reportTargetExistsClause)
+
+	return r.getReports(query, communityId, status, limit, offset)
+}
+
+func (r *ReportRepo) GetCommunityReportsCount(communityId int, status ReportStatus) (int, error) {
+
+	var totalCount int
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM reports WHERE community_id=$1 AND report_status=$2 AND %s`, reportTargetExistsClause)
+
+	if err := r.db.QueryRow(query, communityId, status).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+// GetReports gets reports across all communities with the given status, oldest first
+func (r *ReportRepo) GetReports(status ReportStatus, offset int, limit int) ([]Report, error) {
+
+	query := fmt.Sprintf(`SELECT %s FROM reports WHERE report_status=$1 AND %s
+	ORDER BY created_at ASC LIMIT $2 OFFSET $3`, reportColumns, reportTargetExistsClause)
+
+	return r.getReports(query, status, limit, offset)
+}
+
+func (r *ReportRepo) GetReportsCount(status ReportStatus) (int, error) {
+
+	var totalCount int
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM reports WHERE report_status=$1 AND %s`, reportTargetExistsClause)
+
+	if err := r.db.QueryRow(query, status).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+func (r *ReportRepo) getReports(query string, args ...interface{}) ([]Report, error) {
+
+	var reports []Report
+
+	rows, err := r.db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var report Report
+
+		if err := rows.StructScan(&report); err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// ReportAuthorBan bans the author of the reported content from the report's community, Audit is the
+// audit log entry of the ban
+type ReportAuthorBan struct {
+	UserId     int
+	BanReason  string
+	Expiration *time.Time
+	Audit      *AuditLogEntry
+}
+
+// ResolveReport resolves the report along with every other open report on the same content.
+// when removeContentAudit is set the reported post or comment is deleted, recorded with it in the audit
+// log, and when authorBan is set its author is banned, both in the same transaction. the returned urls
+// are the images of a removed post that nothing else uses anymore, like with DeletePostById
+func (r *ReportRepo) ResolveReport(report *Report, resolvedById int, resolution ReportResolution, removeContentAudit *AuditLogEntry, authorBan *ReportAuthorBan, audit *AuditLogEntry) (*Report, []string, error) {
+
+	var resolvedReport Report
+	var unusedImageUrls []string
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	targetClause := `post_id=$4`
+	var targetId int
+	if report.CommentId != nil {
+		targetClause = `comment_id=$4`
+		targetId = *report.CommentId
+	} else {
+		targetId = *report.PostId
+	}
+
+	resolveQuery := fmt.Sprintf(`UPDATE reports SET report_status='resolved', report_resolution=$1, resolved_by_id=$2, resolved_at=$3
+	WHERE report_status='open' AND %s`, targetClause)
+
+	if _, err := tx.Exec(resolveQuery, resolution, resolvedById, time.Now(), targetId); err != nil {
+		rollBackErr = err
+		return nil, nil, rollBackErr
+	}
+
+	if removeContentAudit != nil && report.CommentId != nil {
+
+		// removed comments are soft deleted so the replies under them stay
+		if err := softDeleteComment(tx, targetId); err != nil {
+			rollBackErr = err
+			return nil, nil, rollBackErr
+		}
+
+		if err := insertAuditLog(tx, removeContentAudit); err != nil {
+			rollBackErr = err
+			return nil, nil, rollBackErr
+		}
+
+	} else if removeContentAudit != nil {
+
+		unusedImageUrls, err = deletePost(tx, targetId, removeContentAudit)
+		if err != nil {
+			rollBackErr = err
+			return nil, nil, rollBackErr
+		}
+	}
+
+	if authorBan != nil {
+		if _, err := createCommunityBan(tx, report.CommunityId, authorBan.UserId, resolvedById, CommunityBanTypeBan, authorBan.BanReason, authorBan.Expiration, authorBan.Audit); err != nil {
+			rollBackErr = err
+			return nil, nil, rollBackErr
+		}
+	}
+
+	getQuery := fmt.Sprintf(`SELECT %s FROM reports WHERE id=$1`, reportColumns)
+
+	if err := tx.QueryRowx(getQuery, report.Id).StructScan(&resolvedReport); err != nil {
+		rollBackErr = err
+		return nil, nil, rollBackErr
+	}
+
+	if audit != nil {
+		audit.After = resolvedReport
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		rollBackErr = err
+		return nil, nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, nil, rollBackErr
+	}
+
+	return &resolvedReport, unusedImageUrls, nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchType string
+
+const (
+	SearchTypePost      SearchType = "post"
+	SearchTypeComment   SearchType = "comment"
+	SearchTypeCommunity SearchType = "community"
+)
+
+var SearchTypes = []SearchType{SearchTypePost, SearchTypeComment, SearchTypeCommunity}
+
+// SearchResult is a post, comment or community matching a search. Title and Snippet are HTML escaped,
+// the matched words wrapped in <mark></mark> are the only markup in them
+type SearchResult struct {
+	ResultType  SearchType `db:"result_type" json:"result_type"`
+	Id          int        `db:"id" json:"id"`
+	CommunityId int        `db:"community_id" json:"community_id"`
+	PostId      *int       `db:"post_id" json:"post_id"` // the post itself or the commented post, nil for communities
+	Title       string     `db:"title" json:"title"`     // post title, the commented post's title or community name
+	Snippet     string     `db:"snippet" json:"snippet"` // fragments of the post content, comment or community description
+	Rank        float64    `db:"rank" json:"rank"`
+	CreatedAt   string     `db:"created_at" json:"created_at"`
+}
+
+type SearchRepo struct {
+	db *sqlx.DB
+}
+
+func NewSearchRepo(db *sqlx.DB) *SearchRepo {
+	return &SearchRepo{
+		db: db,
+	}
+}
+
+// searchMatchesQuery selects the results of the given types matching $1 that user $2 can read, private
+// communities and their content only match for their members. a zero user is an anonymous viewer
+func searchMatchesQuery(searchTypes []SearchType) string {
+
+	visibleCommunityClause := `(c.community_visibility <> 'private' OR c.community_owner_id = $2
+	OR c.id IN (SELECT community_id FROM user_communities WHERE user_id = $2))`
+
+	var parts []string
+
+	for _, searchType := range searchTypes {
+		switch searchType {
+		case SearchTypePost:
+			parts = append(parts, `SELECT 'post' AS result_type, p.id, p.post_community_id AS community_id, p.id AS post_id,
+			p.post_title AS title_text, p.post_content AS snippet_text,
+			ts_rank_cd(p.post_search_vector, q.query) AS rank, p.post_created_at AS created_at
+			FROM posts AS p CROSS JOIN q INNER JOIN communities AS c ON p.post_community_id = c.id
+			WHERE p.post_search_vector @@ q.query AND `+visibleCommunityClause)
+		case SearchTypeComment:
+			parts = append(parts, `SELECT 'comment' AS result_type, pc.id, p.post_community_id AS community_id, p.id AS post_id,
+			p.post_title AS title_text, pc.comment_content AS snippet_text,
+			ts_rank_cd(pc.comment_search_vector, q.query) AS rank, pc.comment_created_at AS created_at
+			FROM post_comments AS pc CROSS JOIN q INNER JOIN posts AS p ON pc.post_id = p.id
+			INNER JOIN communities AS c ON p.post_community_id = c.id
+			WHERE pc.comment_search_vector @@ q.query AND pc.comment_deleted_at IS NULL AND `+visibleCommunityClause)
+		case SearchTypeCommunity:
+			parts = append(parts, `SELECT 'community' AS result_type, c.id, c.id AS community_id, NULL::integer AS post_id,
+			c.community_name AS title_text, COALESCE(c.community_description, '') AS snippet_text,
+			ts_rank_cd(c.community_search_vector, q.query) AS rank, c.community_created_at AS created_at
+			FROM communities AS c CROSS JOIN q
+			WHERE c.community_search_vector @@ q.query AND `+visibleCommunityClause)
+		}
+	}
+
+	return strings.Join(parts, "\nUNION ALL\n")
+}
+
+// htmlEscapeSQL escapes the HTML special characters of a text column. ts_headline reads the escaped
+// characters as entities and not as words, so they are never highlighted
+func htmlEscapeSQL(column string) string {
+	return fmt.Sprintf(`REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`, column)
+}
+
+// Search ranks the posts, comments and communities of the given types matching searchQuery, which takes
+// the web search syntax ("quoted phrases", or, -excluded words). snippets are only built for the returned page
+func (s *SearchRepo) Search(searchQuery string, searchTypes []SearchType, userId int, offset int, limit int) ([]SearchResult, error) {
+
+	var results []SearchResult
+
+	query := fmt.Sprintf(`WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query),
+	matches AS (%[1]s)
+	SELECT result_type, id, community_id, post_id,
+	ts_headline('english', %[2]s, q.query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=TRUE') AS title,
+	ts_headline('english', %[3]s, q.query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" ... "') AS snippet,
+	rank, created_at
+	FROM (SELECT * FROM matches ORDER BY rank DESC, created_at DESC, result_type, id LIMIT $3 OFFSET $4) AS page CROSS JOIN q
+	ORDER BY rank DESC, created_at DESC, result_type, id`, searchMatchesQuery(searchTypes), htmlEscapeSQL("title_text"), htmlEscapeSQL("snippet_text"))
+
+	if err := s.db.Select(&results, query, searchQuery, userId, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *SearchRepo) SearchCount(searchQuery string, searchTypes []SearchType, userId int) (int, error) {
+
+	var count int
+
+	query := fmt.Sprintf(`WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query),
+	matches AS (%s)
+	SELECT COUNT(*) FROM matches`, searchMatchesQuery(searchTypes))
+
+	if err := s.db.QueryRow(query, searchQuery, userId).Scan(&count); err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
@@ -9,9 +9,12 @@ import (
 type SortByStr string
 
 const (
-	SortByNewest    SortByStr = "new"
-	SortByTop       SortByStr = "top" // sort posts by top post likes
-	SortByRelevance SortByStr = "hot" // posts that are 'hot'
+	SortByNewest        SortByStr = "new"
+	SortByTop           SortByStr = "top" // sort by net score, upvotes minus downvotes
+	SortByRelevance     SortByStr = "hot" // posts that are 'hot'
+	SortByOldest        SortByStr = "old"
+	SortByBest          SortByStr = "best"          // comments by the confidence score of their votes
+	SortByControversial SortByStr = "controversial" // comments with many votes split evenly between up and down
 )
 
 type Storage struct {
@@ -22,6 +25,14 @@ type Storage struct {
 	Communities          CommunityRepository
 	Posts                PostRepository
 	PostComments         PostCommentRepository
+	RefreshTokens        RefreshTokenRepository
+	BookmarkCollections  BookmarkCollectionRepository
+	CommunityBans        CommunityBanRepository
+	Reports              ReportRepository
+	AuditLogs            AuditLogRepository
+	Reactions            ReactionRepository
+	Search               SearchRepository
+	Autocomplete         AutocompleteRepository
 }
 
 func NewStorage(db *sqlx.DB) *Storage {
@@ -33,27 +44,44 @@ func NewStorage(db *sqlx.DB) *Storage {
 		Communities:          NewCommunityRepo(db),
 		Posts:                NewPostRepo(db),
 		PostComments:         NewPostCommentRepo(db),
+		RefreshTokens:        NewRefreshTokenRepo(db),
+		BookmarkCollections:  NewBookmarkCollectionRepo(db),
+		CommunityBans:        NewCommunityBanRepo(db),
+		Reports:              NewReportRepo(db),
+		AuditLogs:            NewAuditLogRepo(db),
+		Reactions:            NewReactionRepo(db),
+		Search:               NewSearchRepo(db),
+		Autocomplete:         NewAutocompleteRepo(db),
 	}
 }
 
 type UserRepository interface {
-	DeleteUserById(id int) error
+	DeleteUserById(id int, policy UserDeletionPolicy) error
+	GetReferencedUserUploads(userId int) ([]string, error)
 	GetVerifiedUserByEmail(email string) (*User, error)
+	GetUnverifiedUserByEmail(email string) (*User, error)
 	CreateUserAndInvitation(email string, hashedPassword string, hashedToken string, expiration time.Time) (*User, error)
+	RotateUserInvitation(userId int, hashedToken string, expiration time.Time) (*UserInvitation, error)
+	DeleteExpiredUserInvitations() (int64, error)
+	DeleteStaleUnverifiedUsers(createdBefore time.Time) (int64, error)
 	CreateAdminUser(email string, hashedPassword string) (*User, error)
 	CreateVerifiedUser(email string, hashedPassword string) (*User, error)
 	ActivateUser(hashedToken string) (*User, error)
 	GetUserById(id int) (*User, error)
 	GetUserByUsername(username string) (*User, error)
+	GetPublicUserProfile(userId int) (*PublicUserProfile, error)
 	UpdateUsernameById(id int, username string) (*User, error)
+	UpdateUserProfileById(id int, userImage *string, bio *string, location *string, dateOfBirth *string) (*User, error)
+	CreatePasswordReset(userId int, hashedToken string, expiration time.Time) (*PasswordReset, error)
+	ResetPassword(hashedToken string, hashedPassword string) (*User, error)
 }
 
 type TopicRepository interface {
 	GetTopicByTopicName(topicName string) (*Topic, error)
-	CreateTopic(topicName string) (*Topic, error)
+	CreateTopic(topicName string, audit *AuditLogEntry) (*Topic, error)
 	GetTopicById(topicId int) (*Topic, error)
-	DeleteTopicById(topicId int) error
-	UpdateTopicById(topicId int, topicName string) (*Topic, error)
+	DeleteTopicById(topicId int, audit *AuditLogEntry) error
+	UpdateTopicById(topicId int, topicName string, audit *AuditLogEntry) (*Topic, error)
 	GetTopics(offset int, limit int, search string) ([]Topic, error)
 	GetTopicsCount(search string) (int, error)
 }
@@ -71,7 +99,7 @@ type TopicQueryRepository interface {
 
 type CommunityRepository interface {
 	GetCommunityByName(communityName string) (*Community, error)
-	CreateCommunityWithTopics(communityName string, communityDescription string, communityImage string, communityOwnerId int, communityTopicIds []int) (*CommunityWithTopics, error)
+	CreateCommunityWithTopics(communityName string, communityDescription string, communityImage string, communityOwnerId int, communityVisibility CommunityVisibility, communityTopicIds []int) (*CommunityWithTopics, error)
 	GetCommunityById(communityId int) (*Community, error)
 	CheckCommunityForUser(userId int, communityId int) (bool, error)
 	JoinCommunity(userId int, communityId int) (*UserCommunity, error)
@@ -81,16 +109,45 @@ type CommunityRepository interface {
 	GetCommunityProfile(communityId int) (*CommunityWithMetaData, error)
 	GetRecommendedCommunitiesForUser(userId int, offset int, limit int) ([]CommunityWithMetaData, error)
 	GetRecommendCommunitiesForUserCount(userId int) (int, error)
+	GetUserCommunities(userId int, offset int, limit int) ([]Community, error)
+	GetUserCommunitiesCount(userId int) (int, error)
+	GetCommunityRole(userId int, communityId int) (CommunityRole, error)
+	UpdateCommunityMemberRole(userId int, communityId int, role CommunityRole, audit *AuditLogEntry) (*UserCommunity, error)
+	GetCommunityModerators(communityId int) ([]User, error)
+	UpdateCommunityById(communityId int, communityName string, communityDescription *string, communityImage *string, communityVisibility CommunityVisibility, communityTopicIds []int) (*CommunityWithTopics, error)
+	DeleteCommunityById(communityId int) error
+	CreateOwnershipTransfer(communityId int, fromUserId int, toUserId int, expiration time.Time) (*CommunityOwnershipTransfer, error)
+	GetOwnershipTransfer(communityId int) (*CommunityOwnershipTransfer, error)
+	GetUserOwnershipTransfers(userId int) ([]CommunityOwnershipTransfer, error)
+	DeleteOwnershipTransfer(communityId int) error
+	AcceptOwnershipTransfer(communityId int, toUserId int) (*Community, error)
+	CreateJoinRequest(userId int, communityId int) (*CommunityJoinRequest, error)
+	GetJoinRequest(userId int, communityId int) (*CommunityJoinRequest, error)
+	DeleteJoinRequest(userId int, communityId int) error
+	GetCommunityJoinRequests(communityId int, offset int, limit int) ([]CommunityJoinRequestWithUser, error)
+	GetCommunityJoinRequestsCount(communityId int) (int, error)
+	ApproveJoinRequest(userId int, communityId int) (*UserCommunity, error)
+	CreateCommunityInvite(inviteCode string, communityId int, createdById int, maxUses *int, expiration *time.Time) (*CommunityInvite, error)
+	GetCommunityInviteById(id int) (*CommunityInvite, error)
+	GetUsableCommunityInvite(inviteCode string) (*CommunityInvite, error)
+	GetCommunityInvites(communityId int) ([]CommunityInvite, error)
+	DeleteCommunityInviteById(id int) error
+	AcceptCommunityInvite(inviteCode string, userId int) (*UserCommunity, error)
 }
 
 type PostRepository interface {
 	CreatePost(postTitle string, postContent string, postOwnerId int, postCommunityId int) (*Post, error)
 	CreatePostWithImages(postTitle string, postContent string, postOwnerId int, postCommunityId int, postImageUrls []string) (*PostWithImages, error)
 	GetPostById(id int) (*Post, error)
-	DeletePostById(id int) error
-	CheckPostLike(userId int, postId int) (bool, error)
-	CreatePostLike(userId int, postId int) (*PostLike, error)
-	RemovePostLike(userId int, postId int) error
+	GetPostWithMetaDataById(id int) (*PostWithMetaData, error)
+	SetPostsViewerState(userId int, posts []PostWithMetaData) error
+	UpdatePostById(id int, postTitle string, postContent string, postImageUrls []string, editedById int) (*PostWithImages, error)
+	GetPostRevisions(postId int, offset int, limit int) ([]PostRevision, error)
+	GetPostRevisionsCount(postId int) (int, error)
+	DeletePostById(id int, audit *AuditLogEntry) ([]string, error)
+	GetPostVote(userId int, postId int) (*PostVote, error)
+	VotePost(userId int, postId int, voteValue int) (*PostVote, error)
+	RemovePostVote(userId int, postId int) error
 	CheckPostBookmark(userId int, postId int) (bool, error)
 	CreatePostBookmark(userId int, postId int) (*PostBookmark, error)
 	RemovePostBookmark(userId int, postId int) error
@@ -100,18 +157,99 @@ type PostRepository interface {
 	GetUserPostsFeedCount(userId int, n int) (int, error)
 	GetPostsFeed(n int, skip int, limit int, sortBy SortByStr) ([]PostWithMetaData, error)
 	GetPostsFeedCount(n int) (int, error)
+	GetCommunityPostsByCursor(communityId int, sortBy SortByStr, search string, cursor *PostCursor, limit int) ([]PostWithMetaData, *PostCursor, error)
+	GetUserPostsFeedByCursor(userId int, n int, sortBy SortByStr, cursor *PostCursor, limit int) ([]PostWithMetaData, *PostCursor, error)
+	GetPostsFeedByCursor(n int, sortBy SortByStr, cursor *PostCursor, limit int) ([]PostWithMetaData, *PostCursor, error)
+	UpdateHotScores() (int64, error)
+	GetUserPosts(userId int, skip int, limit int) ([]PostWithMetaData, error)
+	GetUserPostsCount(userId int) (int, error)
+	GetUserBookmarkedPosts(userId int, collectionId *int, skip int, limit int) ([]PostWithMetaData, error)
+	GetUserBookmarkedPostsCount(userId int, collectionId *int) (int, error)
+	GetUserLikedPosts(userId int, skip int, limit int) ([]PostWithMetaData, error)
+	GetUserLikedPostsCount(userId int) (int, error)
 }
 
 type PostCommentRepository interface {
-	DeletePostCommentById(id int) error
+	DeletePostCommentById(id int, audit *AuditLogEntry) error
 	GetPostCommentById(id int) (*PostComment, error)
+	UpdatePostCommentById(id int, commentContent string, editedById int) (*PostComment, error)
+	GetCommentRevisions(commentId int, offset int, limit int) ([]CommentRevision, error)
+	GetCommentRevisionsCount(commentId int) (int, error)
+	GetCommentTree(postId int, cursor *CommentCursor, maxDepth int, maxChildren int, maxNodes int) ([]CommentTreeNode, *string, error)
 	CreatePostComment(commentContent string, commentOwnerId int, postId int) (*PostComment, error)
 	CreateChildPostComment(commentContent string, commentOwnerId int, postId int, parentCommentId int) (*PostComment, error)
-	CheckCommentLike(userId int, commentId int) (bool, error)
-	CreateCommentLike(userId int, commentId int) (*PostCommentLike, error)
-	RemoveCommentLike(userId int, commentId int) error
-	GetPostComments(postId int, offset int, limit int) ([]PostCommentWithMetaData, error)
+	GetCommentVote(userId int, commentId int) (*PostCommentVote, error)
+	VoteComment(userId int, commentId int, voteValue int) (*PostCommentVote, error)
+	RemoveCommentVote(userId int, commentId int) error
+	GetPostComments(postId int, offset int, limit int, sortBy SortByStr) ([]PostCommentWithMetaData, error)
 	GetPostCommentsCount(postId int) (int, error)
-	GetCommentReplies(commentId int, offset int, limit int) ([]PostCommentWithMetaData, error)
+	GetCommentReplies(commentId int, offset int, limit int, sortBy SortByStr) ([]PostCommentWithMetaData, error)
 	GetCommentRepliesCount(commentId int) (int, error)
+	GetUserComments(userId int, offset int, limit int) ([]PostCommentWithMetaData, error)
+	GetUserCommentsCount(userId int) (int, error)
+}
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(hashedToken string, userId int, familyId string, expiration time.Time) (*RefreshToken, error)
+	GetRefreshToken(hashedToken string) (*RefreshToken, error)
+	RotateRefreshToken(hashedToken string, newHashedToken string, expiration time.Time) (*RefreshToken, error)
+	RevokeRefreshTokenFamily(familyId string) error
+	RevokeUserRefreshTokens(userId int) error
+}
+
+type BookmarkCollectionRepository interface {
+	CreateBookmarkCollection(collectionName string, collectionOwnerId int) (*BookmarkCollection, error)
+	GetBookmarkCollectionById(id int) (*BookmarkCollection, error)
+	GetBookmarkCollectionByName(collectionName string, collectionOwnerId int) (*BookmarkCollection, error)
+	GetUserBookmarkCollections(userId int) ([]BookmarkCollectionWithMetaData, error)
+	UpdateBookmarkCollectionName(id int, collectionName string) (*BookmarkCollection, error)
+	DeleteBookmarkCollectionById(id int) error
+	UpdatePostBookmarkCollection(userId int, postId int, collectionId *int) (*PostBookmark, error)
+}
+
+type CommunityBanRepository interface {
+	CreateCommunityBan(communityId int, userId int, bannedById int, banType CommunityBanType, banReason string, expiration *time.Time, audit *AuditLogEntry) (*CommunityBan, error)
+	GetActiveCommunityBan(userId int, communityId int) (*CommunityBan, error)
+	GetCommunityBans(communityId int, offset int, limit int) ([]CommunityBanWithUser, error)
+	GetCommunityBansCount(communityId int) (int, error)
+	DeleteCommunityBan(userId int, communityId int, audit *AuditLogEntry) error
+}
+
+type ReportRepository interface {
+	CreateReport(reporterId int, communityId int, postId *int, commentId *int, reportReason ReportReason, reportDetails string) (*Report, error)
+	CheckOpenPostReport(reporterId int, postId int) (bool, error)
+	CheckOpenCommentReport(reporterId int, commentId int) (bool, error)
+	GetReportById(id int) (*Report, error)
+	GetCommunityReports(communityId int, status ReportStatus, offset int, limit int) ([]Report, error)
+	GetCommunityReportsCount(communityId int, status ReportStatus) (int, error)
+	GetReports(status ReportStatus, offset int, limit int) ([]Report, error)
+	GetReportsCount(status ReportStatus) (int, error)
+	ResolveReport(report *Report, resolvedById int, resolution ReportResolution, removeContentAudit *AuditLogEntry, authorBan *ReportAuthorBan, audit *AuditLogEntry) (*Report, []string, error)
+}
+
+type AuditLogRepository interface {
+	GetAuditLogs(filter AuditLogFilter, offset int, limit int) ([]AuditLog, error)
+	GetAuditLogsCount(filter AuditLogFilter) (int, error)
+}
+
+type ReactionRepository interface {
+	GetCommunityAllowedReactions(communityId int) ([]string, error)
+	UpdateCommunityAllowedReactions(communityId int, allowedReactions []string) ([]string, error)
+	CheckPostReaction(userId int, postId int, reaction string) (bool, error)
+	CreatePostReaction(userId int, postId int, reaction string) (*PostReaction, error)
+	RemovePostReaction(userId int, postId int, reaction string) error
+	CheckCommentReaction(userId int, commentId int, reaction string) (bool, error)
+	CreateCommentReaction(userId int, commentId int, reaction string) (*PostCommentReaction, error)
+	RemoveCommentReaction(userId int, commentId int, reaction string) error
+	SetPostsReactions(userId int, posts []PostWithMetaData) error
+	SetCommentsReactions(userId int, comments []*PostCommentWithMetaData) error
+}
+
+type SearchRepository interface {
+	Search(searchQuery string, searchTypes []SearchType, userId int, offset int, limit int) ([]SearchResult, error)
+	SearchCount(searchQuery string, searchTypes []SearchType, userId int) (int, error)
+}
+
+type AutocompleteRepository interface {
+	Autocomplete(prefix string, suggestionTypes []AutocompleteType, limit int) ([]AutocompleteSuggestion, error)
 }
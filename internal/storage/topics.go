@@ -33,15 +33,42 @@ func (t *TopicRepo) GetTopicByTopicName(topicName string) (*Topic, error) {
 	return &topic, nil
 }
 
-func (t *TopicRepo) CreateTopic(topicName string) (*Topic, error) {
+func (t *TopicRepo) CreateTopic(topicName string, audit *AuditLogEntry) (*Topic, error) {
 
 	var topic Topic
 
+	tx, err := t.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
 	query := `INSERT INTO topics(topic_name) VALUES($1) RETURNING 
 	id,topic_name`
 
-	if err := t.db.QueryRowx(query, topicName).StructScan(&topic); err != nil {
-		return nil, err
+	if err := tx.QueryRowx(query, topicName).StructScan(&topic); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if audit != nil {
+		audit.TargetId = topic.Id
+		audit.After = topic
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
 	}
 
 	return &topic, nil
@@ -60,26 +87,73 @@ func (t *TopicRepo) GetTopicById(topicId int) (*Topic, error) {
 	return &topic, nil
 }
 
-func (t *TopicRepo) DeleteTopicById(topicId int) error {
+func (t *TopicRepo) DeleteTopicById(topicId int, audit *AuditLogEntry) error {
 
-	query := `DELETE FROM topics WHERE id=$1`
-
-	_, err := t.db.Exec(query, topicId)
+	tx, err := t.db.Beginx()
 	if err != nil {
 		return err
 	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `DELETE FROM topics WHERE id=$1`
+
+	if _, err := tx.Exec(query, topicId); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
 
 	return nil
 }
 
-func (t *TopicRepo) UpdateTopicById(topicId int, topicName string) (*Topic, error) {
+func (t *TopicRepo) UpdateTopicById(topicId int, topicName string, audit *AuditLogEntry) (*Topic, error) {
 
 	var topic Topic
 
+	tx, err := t.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
 	query := `UPDATE topics SET topic_name=$1 WHERE id=$2 RETURNING id,topic_name`
 
-	if err := t.db.QueryRowx(query, topicName, topicId).StructScan(&topic); err != nil {
-		return nil, err
+	if err := tx.QueryRowx(query, topicName, topicId).StructScan(&topic); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if audit != nil {
+		audit.After = topic
+	}
+
+	if err := insertAuditLog(tx, audit); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
 	}
 
 	return &topic, nil
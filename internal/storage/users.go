@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -13,9 +16,19 @@ const (
 	UserRoleUser  UserRole = "user"
 )
 
+// what happens to a deleted user's posts, comments, likes and bookmarks
+type UserDeletionPolicy string
+
+const (
+	UserDeletionPolicyAnonymize UserDeletionPolicy = "anonymize" // keep content, scrub the user's profile
+	UserDeletionPolicyCascade   UserDeletionPolicy = "cascade"   // delete the user and everything they created
+)
+
+var ErrNoCommunitySuccessor = errors.New("no user available to take over community ownership")
+
 type User struct {
 	Id          int     `db:"id" json:"id"`
-	Email       string  `db:"email" json:"email"`
+	Email       string  `db:"email" json:"email,omitempty"`
 	Password    string  `db:"password" json:"-"`
 	Username    *string `db:"username" json:"username"`
 	IsVerified  bool    `db:"is_verified" json:"is_verified"`
@@ -29,12 +42,37 @@ type User struct {
 	UpdatedAt   *string `db:"updated_at" json:"updated_at"`
 }
 
+// PublicUser is the part of a user that can be shown to anyone, it never includes the email
+type PublicUser struct {
+	Id        int     `db:"id" json:"id"`
+	Username  *string `db:"username" json:"username"`
+	UserImage *string `db:"user_image" json:"user_image"`
+	Bio       *string `db:"bio" json:"bio"`
+	Location  *string `db:"location" json:"location"`
+	CreatedAt string  `db:"created_at" json:"created_at"`
+}
+
+type PublicUserProfile struct {
+	PublicUser
+	PostsCount       int `db:"posts_count" json:"posts_count"`
+	CommentsCount    int `db:"comments_count" json:"comments_count"`
+	CommunitiesCount int `db:"communities_count" json:"communities_count"`
+}
+
 type UserInvitation struct {
 	Token      string `db:"token" json:"token"`
 	UserId     int    `db:"user_id" json:"user_id"`
 	Expiration string `db:"expiration" json:"expiration"`
 }
 
+type PasswordReset struct {
+	Token      string  `db:"token" json:"-"`
+	UserId     int     `db:"user_id" json:"user_id"`
+	Expiration string  `db:"expiration" json:"expiration"`
+	UsedAt     *string `db:"used_at" json:"used_at"`
+	CreatedAt  string  `db:"created_at" json:"created_at"`
+}
+
 type UserRepo struct {
 	db *sqlx.DB
 }
@@ -45,11 +83,146 @@ func NewUserRepo(db *sqlx.DB) *UserRepo {
 	}
 }
 
-func (u *UserRepo) DeleteUserById(id int) error {
-	//TODO implement me
+// DeleteUserById deletes a user according to the deletion policy, communities owned by
+// the user are handed over to a site admin in the same transaction
+func (u *UserRepo) DeleteUserById(id int, policy UserDeletionPolicy) error {
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if rollBackErr = transferOwnedCommunities(tx, id); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	switch policy {
+	case UserDeletionPolicyCascade:
+
+		// posts, comments, likes, bookmarks and memberships cascade from users
+		if _, err := tx.Exec(`DELETE FROM users WHERE id=$1`, id); err != nil {
+			rollBackErr = err
+			return rollBackErr
+		}
+
+	case UserDeletionPolicyAnonymize:
+
+		// posts, comments and likes stay attributed to a scrubbed user row,
+		// private data tied to the user is removed
+		cleanupQueries := []string{
+			`DELETE FROM post_bookmarks WHERE bookmarked_by_id=$1`,
+			`DELETE FROM bookmark_collections WHERE collection_owner_id=$1`,
+			`DELETE FROM community_ownership_transfers WHERE from_user_id=$1 OR to_user_id=$1`,
+			`DELETE FROM community_join_requests WHERE user_id=$1`,
+			`DELETE FROM user_communities WHERE user_id=$1`,
+			`DELETE FROM user_topic_preferences WHERE user_id=$1`,
+			`DELETE FROM user_invitations WHERE user_id=$1`,
+			`DELETE FROM password_resets WHERE user_id=$1`,
+			`DELETE FROM refresh_tokens WHERE user_id=$1`,
+		}
+
+		for _, query := range cleanupQueries {
+			if _, err := tx.Exec(query, id); err != nil {
+				rollBackErr = err
+				return rollBackErr
+			}
+		}
+
+		anonymizeQuery := `UPDATE users SET email=$2, password='', username=NULL, is_verified=FALSE, user_image=NULL,
+		bio=NULL, location=NULL, date_of_birth=NULL, deleted_at=NOW(), updated_at=NOW() WHERE id=$1`
+
+		if _, err := tx.Exec(anonymizeQuery, id, fmt.Sprintf("deleted-user-%d@deleted.invalid", id)); err != nil {
+			rollBackErr = err
+			return rollBackErr
+		}
+
+	default:
+		rollBackErr = fmt.Errorf("invalid user deletion policy %q", policy)
+		return rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return rollBackErr
+	}
+
 	return nil
 }
 
+// hands every community owned by the user to the longest standing admin
+func transferOwnedCommunities(tx *sqlx.Tx, userId int) error {
+
+	var ownedCommunityIds []int
+
+	if err := tx.Select(&ownedCommunityIds, `SELECT id FROM communities WHERE community_owner_id=$1`, userId); err != nil {
+		return err
+	}
+
+	for _, communityId := range ownedCommunityIds {
+
+		var newOwnerId int
+
+		// the longest standing moderator of the community takes over, otherwise a site admin
+		moderatorQuery := `SELECT user_id FROM user_communities 
+		WHERE community_id=$1 AND role='moderator' AND user_id<>$2 
+		ORDER BY joined_at ASC LIMIT 1`
+
+		if err := tx.QueryRowx(moderatorQuery, communityId, userId).Scan(&newOwnerId); err != nil {
+
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			adminQuery := `SELECT id FROM users WHERE role='admin' AND id<>$1 AND deleted_at IS NULL ORDER BY id ASC LIMIT 1`
+
+			if err := tx.QueryRowx(adminQuery, userId).Scan(&newOwnerId); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrNoCommunitySuccessor
+				}
+				return err
+			}
+		}
+
+		transferQuery := `UPDATE communities SET community_owner_id=$1, community_updated_at=NOW() WHERE id=$2`
+
+		if _, err := tx.Exec(transferQuery, newOwnerId, communityId); err != nil {
+			return err
+		}
+
+		// owners are not stored as members of their own communities
+		if _, err := tx.Exec(`DELETE FROM user_communities WHERE user_id=$1 AND community_id=$2`, newOwnerId, communityId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetReferencedUserUploads returns urls under the user's upload prefix that are still in use,
+// e.g. images of posts kept after anonymizing or of communities handed to a new owner
+func (u *UserRepo) GetReferencedUserUploads(userId int) ([]string, error) {
+
+	var urls []string
+
+	uploadPattern := fmt.Sprintf("%%/uploads/userId-%d/%%", userId)
+
+	query := `SELECT post_image_url FROM post_images WHERE post_image_url LIKE $1
+	UNION
+	SELECT community_image FROM communities WHERE community_image LIKE $1`
+
+	if err := u.db.Select(&urls, query, uploadPattern); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
 func (u *UserRepo) GetVerifiedUserByEmail(email string) (*User, error) {
 
 	var user User
@@ -64,6 +237,86 @@ func (u *UserRepo) GetVerifiedUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// GetUnverifiedUserByEmail gets the most recently registered unverified account for an email
+func (u *UserRepo) GetUnverifiedUserByEmail(email string) (*User, error) {
+
+	var user User
+
+	query := `SELECT id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at 
+	FROM users WHERE email=$1 AND is_verified=FALSE AND deleted_at IS NULL
+	ORDER BY created_at DESC LIMIT 1`
+
+	if err := u.db.QueryRowx(query, email).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// RotateUserInvitation replaces every invitation of the user with a new token
+func (u *UserRepo) RotateUserInvitation(userId int, hashedToken string, expiration time.Time) (*UserInvitation, error) {
+
+	var userInvitation UserInvitation
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM user_invitations WHERE user_id=$1`, userId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	query := `INSERT INTO user_invitations(token, user_id, expiration) VALUES($1,$2,$3) RETURNING token, user_id, expiration`
+
+	if err := tx.QueryRowx(query, hashedToken, userId, expiration).StructScan(&userInvitation); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &userInvitation, nil
+}
+
+func (u *UserRepo) DeleteExpiredUserInvitations() (int64, error) {
+
+	query := `DELETE FROM user_invitations WHERE expiration <= $1`
+
+	result, err := u.db.Exec(query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteStaleUnverifiedUsers deletes accounts that were never verified and were created before createdBefore.
+// Accounts with a pending invitation are kept so a freshly resent link keeps working
+func (u *UserRepo) DeleteStaleUnverifiedUsers(createdBefore time.Time) (int64, error) {
+
+	query := `DELETE FROM users AS u
+	WHERE u.is_verified=FALSE AND u.deleted_at IS NULL AND u.created_at < $1
+	AND NOT EXISTS (SELECT 1 FROM user_invitations AS ui WHERE ui.user_id=u.id AND ui.expiration > $2)`
+
+	result, err := u.db.Exec(query, createdBefore, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (u *UserRepo) CreateUserAndInvitation(email string, hashedPassword string, hashedToken string, expiration time.Time) (*User, error) {
 
 	var user User
@@ -185,11 +438,30 @@ func (u *UserRepo) GetUserByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
+func (u *UserRepo) GetPublicUserProfile(userId int) (*PublicUserProfile, error) {
+
+	var profile PublicUserProfile
+
+	query := `SELECT id, username, user_image, bio, location, created_at,
+		(SELECT COUNT(*) FROM posts WHERE post_owner_id=u.id AND post_community_id NOT IN (SELECT id FROM communities WHERE community_visibility='private')) AS posts_count,
+		(SELECT COUNT(*) FROM post_comments WHERE comment_owner_id=u.id AND comment_deleted_at IS NULL AND post_id NOT IN (SELECT p.id FROM posts AS p 
+		  INNER JOIN communities AS c ON p.post_community_id = c.id WHERE c.community_visibility='private')) AS comments_count,
+		(SELECT COUNT(*) FROM communities AS c WHERE (c.community_owner_id=u.id 
+		  OR c.id IN (SELECT community_id FROM user_communities WHERE user_id=u.id)) AND c.community_visibility <> 'private') AS communities_count
+	FROM users AS u WHERE id=$1`
+
+	if err := u.db.QueryRowx(query, userId).StructScan(&profile); err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
 func (u *UserRepo) UpdateUsernameById(id int, username string) (*User, error) {
 
 	var user User
 
-	query := `UPDATE users SET username=$1 WHERE id=$2
+	query := `UPDATE users SET username=$1, updated_at=NOW() WHERE id=$2
 	RETURNING id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at`
 
 	if err := u.db.QueryRowx(query, username, id).StructScan(&user); err != nil {
@@ -198,3 +470,111 @@ func (u *UserRepo) UpdateUsernameById(id int, username string) (*User, error) {
 
 	return &user, nil
 }
+
+// UpdateUserProfileById overwrites the profile fields, nil values clear the field
+func (u *UserRepo) UpdateUserProfileById(id int, userImage *string, bio *string, location *string, dateOfBirth *string) (*User, error) {
+
+	var user User
+
+	query := `UPDATE users SET user_image=$1, bio=$2, location=$3, date_of_birth=$4, updated_at=NOW() WHERE id=$5
+	RETURNING id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at`
+
+	if err := u.db.QueryRowx(query, userImage, bio, location, dateOfBirth, id).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u *UserRepo) CreatePasswordReset(userId int, hashedToken string, expiration time.Time) (*PasswordReset, error) {
+
+	var passwordReset PasswordReset
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// a new reset request supersedes any earlier unused tokens for this user
+	invalidateQuery := `UPDATE password_resets SET used_at=NOW() WHERE user_id=$1 AND used_at IS NULL`
+
+	if _, err := tx.Exec(invalidateQuery, userId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	query := `INSERT INTO password_resets(token, user_id, expiration) VALUES($1,$2,$3) RETURNING 
+	token, user_id, expiration, used_at, created_at`
+
+	if err := tx.QueryRowx(query, hashedToken, userId, expiration).StructScan(&passwordReset); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &passwordReset, nil
+}
+
+// ResetPassword consumes a password reset token and sets the user's new password.
+// A token is only accepted once, before its expiration, and only if the password
+// has not been changed since the token was issued.
+func (u *UserRepo) ResetPassword(hashedToken string, hashedPassword string) (*User, error) {
+
+	var user User
+	var passwordReset PasswordReset
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	passwordResetQuery := `SELECT pr.token, pr.user_id, pr.expiration, pr.used_at, pr.created_at 
+	FROM password_resets AS pr INNER JOIN users AS u ON pr.user_id=u.id 
+	WHERE pr.token=$1 AND pr.expiration > $2 AND pr.used_at IS NULL 
+	AND (u.password_changed_at IS NULL OR pr.created_at > u.password_changed_at)
+	FOR UPDATE OF pr`
+
+	if err := tx.QueryRowx(passwordResetQuery, hashedToken, time.Now()).StructScan(&passwordReset); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	updateUserQuery := `UPDATE users SET password=$1, password_changed_at=NOW(), updated_at=NOW() WHERE id=$2 RETURNING
+	id, email, password, username, is_verified, role, user_image, bio, location, date_of_birth, verified_at, created_at, updated_at`
+
+	if err := tx.QueryRowx(updateUserQuery, hashedPassword, passwordReset.UserId).StructScan(&user); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	// mark this token and any other outstanding tokens for the user as used
+	invalidateQuery := `UPDATE password_resets SET used_at=NOW() WHERE user_id=$1 AND used_at IS NULL`
+
+	if _, err := tx.Exec(invalidateQuery, passwordReset.UserId); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		rollBackErr = err
+		return nil, rollBackErr
+	}
+
+	return &user, nil
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -73,3 +74,15 @@ func IsPasswordStrong(password string) bool {
 		return isPasswordStrong
 	}
 }
+
+// CalculateAge returns the number of full years between dateOfBirth and now
+func CalculateAge(dateOfBirth time.Time, now time.Time) int {
+
+	age := now.Year() - dateOfBirth.Year()
+
+	if now.Month() < dateOfBirth.Month() || (now.Month() == dateOfBirth.Month() && now.Day() < dateOfBirth.Day()) {
+		age--
+	}
+
+	return age
+}